@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/auth"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+// exportUserData returns everything we store about the user as one JSON
+// document. Secrets such as password hashes and TOTP seeds are left out.
+func exportUserData(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type profile struct {
+			meView
+			EmailVerifiedAt *time.Time `json:"email_verified_at"`
+			TOTPEnabled     bool       `json:"totp_enabled"`
+		}
+		type session struct {
+			ID         uuid.UUID `json:"id"`
+			CreatedAt  time.Time `json:"created_at"`
+			LastUsedAt time.Time `json:"last_used_at"`
+			ExpiresAt  time.Time `json:"expires_at"`
+			UserAgent  string    `json:"user_agent"`
+			IPAddress  string    `json:"ip_address"`
+		}
+		type identity struct {
+			Provider    string    `json:"provider"`
+			Subject     string    `json:"subject"`
+			Email       string    `json:"email"`
+			CreatedAt   time.Time `json:"created_at"`
+			LastLoginAt time.Time `json:"last_login_at"`
+		}
+		// chirpRelation is a like or rechirp of a chirp by the user.
+		type chirpRelation struct {
+			ChirpID   uuid.UUID `json:"chirp_id"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		// userRelation is a follow, block or mute between the user and
+		// another account.
+		type userRelation struct {
+			UserID    uuid.UUID `json:"user_id"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		type resBody struct {
+			ExportedAt           time.Time                 `json:"exported_at"`
+			Profile              profile                   `json:"profile"`
+			Chirps               []chirpView               `json:"chirps"`
+			Likes                []chirpRelation           `json:"likes"`
+			Rechirps             []chirpRelation           `json:"rechirps"`
+			Following            []userRelation            `json:"following"`
+			Followers            []userRelation            `json:"followers"`
+			Blocks               []userRelation            `json:"blocks"`
+			Mutes                []userRelation            `json:"mutes"`
+			Sessions             []session                 `json:"sessions"`
+			PersonalAccessTokens []personalAccessTokenView `json:"personal_access_tokens"`
+			Identities           []identity                `json:"identities"`
+		}
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		res := resBody{
+			ExportedAt: time.Now().UTC(),
+			Profile: profile{
+				meView:      newMeView(user),
+				TOTPEnabled: user.TotpEnabledAt.Valid,
+			},
+			Likes:                []chirpRelation{},
+			Rechirps:             []chirpRelation{},
+			Following:            []userRelation{},
+			Followers:            []userRelation{},
+			Blocks:               []userRelation{},
+			Mutes:                []userRelation{},
+			Sessions:             []session{},
+			PersonalAccessTokens: []personalAccessTokenView{},
+			Identities:           []identity{},
+		}
+		if user.EmailVerifiedAt.Valid {
+			res.Profile.EmailVerifiedAt = &user.EmailVerifiedAt.Time
+		}
+		chirps, err := cfg.db.GetChirpsAllByUserID(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res.Chirps = newChirpViews(chirps)
+		likes, err := cfg.db.GetUserLikes(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, like := range likes {
+			res.Likes = append(res.Likes, chirpRelation{ChirpID: like.ChirpID, CreatedAt: like.CreatedAt})
+		}
+		rechirps, err := cfg.db.GetUserRechirps(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, rechirp := range rechirps {
+			res.Rechirps = append(res.Rechirps, chirpRelation{ChirpID: rechirp.ChirpID, CreatedAt: rechirp.CreatedAt})
+		}
+		follows, err := cfg.db.GetUserFollows(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, follow := range follows {
+			if follow.FollowerID == user.ID {
+				res.Following = append(res.Following, userRelation{UserID: follow.FolloweeID, CreatedAt: follow.CreatedAt})
+			} else {
+				res.Followers = append(res.Followers, userRelation{UserID: follow.FollowerID, CreatedAt: follow.CreatedAt})
+			}
+		}
+		blocks, err := cfg.db.GetUserBlocks(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, block := range blocks {
+			res.Blocks = append(res.Blocks, userRelation{UserID: block.BlockedID, CreatedAt: block.CreatedAt})
+		}
+		mutes, err := cfg.db.GetUserMutes(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, mute := range mutes {
+			res.Mutes = append(res.Mutes, userRelation{UserID: mute.MutedID, CreatedAt: mute.CreatedAt})
+		}
+		sessions, err := cfg.db.GetUserSessions(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, row := range sessions {
+			res.Sessions = append(res.Sessions, session{
+				ID:         row.FamilyID,
+				CreatedAt:  row.SessionStartedAt,
+				LastUsedAt: row.LastUsedAt,
+				ExpiresAt:  row.ExpiresAt,
+				UserAgent:  row.UserAgent,
+				IPAddress:  row.IpAddress,
+			})
+		}
+		personalAccessTokens, err := cfg.db.GetUserPersonalAccessTokens(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, token := range personalAccessTokens {
+			res.PersonalAccessTokens = append(res.PersonalAccessTokens, newPersonalAccessTokenView(token))
+		}
+		identities, err := cfg.db.GetUserIdentities(r.Context(), user.ID)
+		if err != nil {
+			errorMessage := "Cannot export data"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, row := range identities {
+			res.Identities = append(res.Identities, identity{
+				Provider:    row.Provider,
+				Subject:     row.Subject,
+				Email:       row.Email,
+				CreatedAt:   row.CreatedAt,
+				LastLoginAt: row.LastLoginAt,
+			})
+		}
+		data, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.json"`)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// deleteAccount schedules the account for deletion after the grace period
+// and signs it out everywhere. Signing in again and cancelling the deletion
+// keeps the account. With no grace period the account is deleted at once.
+func deleteAccount(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reqBody struct {
+			Password     string `json:"password"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		type resBody struct {
+			DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+		}
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		accountKey := loginAccountKey(user.Email)
+		if !allowLoginAttempt(w, r, cfg, accountKey) {
+			return
+		}
+		_, err = cfg.passwords.Verify(req.Password, user.HashedPassword)
+		if errors.Is(err, auth.ErrPasswordDisabled) {
+			errorMessage := "Account has no password, set one with a password reset first"
+			responseError(w, errorMessage, 403)
+			return
+		}
+		if err != nil {
+			failLoginAttempt(r, cfg, accountKey)
+			errorMessage := "Incorrect password"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		if user.TotpEnabledAt.Valid {
+			ok, err := checkSecondFactor(r.Context(), cfg, user, req.Code, req.RecoveryCode)
+			if err != nil {
+				errorMessage := "Cannot verify code"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			if !ok {
+				failLoginAttempt(r, cfg, accountKey)
+				errorMessage := "Invalid code"
+				responseError(w, errorMessage, 401)
+				return
+			}
+		}
+		succeedLoginAttempt(r, cfg, accountKey)
+		if cfg.accountDeletionGrace <= 0 {
+			if err := cfg.db.DeleteUser(r.Context(), user.ID); err != nil {
+				errorMessage := "Cannot delete account"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			w.WriteHeader(204)
+			return
+		}
+		user, err = cfg.db.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+			ID:                  user.ID,
+			DeletionScheduledAt: sql.NullTime{Time: time.Now().Add(cfg.accountDeletionGrace), Valid: true},
+		})
+		if err != nil {
+			errorMessage := "Cannot delete account"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if err := cfg.db.RevokeAllUserRefreshTokens(r.Context(), user.ID); err != nil {
+			log.Printf("Error revoking refresh tokens: %s\n", err)
+		}
+		if err := cfg.db.RevokeAllUserPersonalAccessTokens(r.Context(), user.ID); err != nil {
+			log.Printf("Error revoking personal access tokens: %s\n", err)
+		}
+		data, err := json.Marshal(resBody{
+			DeletionScheduledAt: user.DeletionScheduledAt.Time,
+		})
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(202)
+		w.Write(data)
+	}
+}
+
+func cancelAccountDeletion(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		cancelled, err := cfg.db.CancelUserDeletion(r.Context(), userID)
+		if err != nil {
+			errorMessage := "Cannot cancel account deletion"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if cancelled == 0 {
+			errorMessage := "Account is not scheduled for deletion"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		w.WriteHeader(204)
+	}
+}
+
+// purgeDeletedAccounts hard deletes accounts whose grace period has passed.
+// Chirps, tokens and identities go with them through ON DELETE CASCADE.
+func purgeDeletedAccounts(ctx context.Context, cfg *apiConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := cfg.db.PurgeDeletedUsers(ctx)
+		if err != nil {
+			log.Printf("Error purging deleted accounts: %s\n", err)
+		} else if deleted > 0 {
+			log.Printf("Purged %d deleted accounts\n", deleted)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
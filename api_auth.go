@@ -1,8 +1,13 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,15 +21,6 @@ func login(cfg *apiConfig) http.HandlerFunc {
 			Email    string `json:"email"`
 			Password string `json:"password"`
 		}
-		type resBody struct {
-			ID           uuid.UUID `json:"id"`
-			CreatedAt    time.Time `json:"created_at"`
-			UpdatedAt    time.Time `json:"updated_at"`
-			Email        string    `json:"email"`
-			Token        string    `json:"token"`
-			RefreshToken string    `json:"refresh_token"`
-			IsChirpyRed  bool      `json:"is_chirpy_red"`
-		}
 		decoder := json.NewDecoder(r.Body)
 		req := reqBody{}
 		err := decoder.Decode(&req)
@@ -33,66 +29,191 @@ func login(cfg *apiConfig) http.HandlerFunc {
 			responseError(w, errorMessage, http.StatusBadRequest)
 			return
 		}
+		accountKey := loginAccountKey(req.Email)
+		if !allowLoginAttempt(w, r, cfg, accountKey) {
+			return
+		}
 		user, err := cfg.db.GetUserByEmail(r.Context(), req.Email)
 		if err != nil {
+			failLoginAttempt(r, cfg, accountKey)
 			errorMessage := "Unauthorized"
 			responseError(w, errorMessage, 401)
 			return
 		}
-		if err := auth.CheckPasswordHash(req.Password, user.HashedPassword); err != nil {
+		needsRehash, err := cfg.passwords.Verify(req.Password, user.HashedPassword)
+		if err != nil {
+			failLoginAttempt(r, cfg, accountKey)
 			errorMessage := "Unauthorized"
 			responseError(w, errorMessage, 401)
 			return
 		}
-		jwtTokenExpiration := 1 * time.Hour
-		jwtToken, err := auth.MakeJWT(user.ID, cfg.jwtSignString, jwtTokenExpiration)
-		if err != nil {
-			errorMessage := "JWT issue"
-			responseError(w, errorMessage, 500)
-			return
-		}
-
-		refreshToken, err := auth.MakeRefreshToken()
-		if err != nil {
-			errorMessage := "Refresh token issue"
-			responseError(w, errorMessage, 500)
-			return
-		}
-		_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-			Token:     refreshToken,
-			UserID:    user.ID,
-			ExpiresAt: time.Now().Add(60 * 24 * time.Hour),
-		})
-		if err != nil {
-			errorMessage := "Refresh token issue"
-			responseError(w, errorMessage, 500)
-			return
-		}
-		res := resBody{
-			ID:           user.ID,
-			CreatedAt:    user.CreatedAt,
-			UpdatedAt:    user.UpdatedAt,
-			Email:        user.Email,
-			Token:        jwtToken,
-			RefreshToken: refreshToken,
-			IsChirpyRed:  user.IsChirpyRed,
+		if needsRehash {
+			rehashPassword(r, cfg, user, req.Password)
 		}
-		data, err := json.Marshal(res)
-		if err != nil {
-			errorMessage := "Cannot marshal response"
-			responseError(w, errorMessage, 500)
+		if !user.TotpEnabledAt.Valid {
+			succeedLoginAttempt(r, cfg, accountKey)
+			responseLogin(w, r, cfg, user)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(200)
-		w.Write(data)
+		responseMFARequired(w, r, cfg, user)
+	}
+}
+
+// responseMFARequired answers a correct first factor of a user with TOTP
+// enabled with a short-lived ticket for POST /api/login/mfa.
+func responseMFARequired(w http.ResponseWriter, r *http.Request, cfg *apiConfig, user database.User) {
+	type resBody struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFATicket   string `json:"mfa_ticket"`
+	}
+	mfaTicket, err := auth.MakeToken()
+	if err != nil {
+		errorMessage := "MFA ticket issue"
+		responseError(w, errorMessage, 500)
+		return
+	}
+	err = cfg.db.CreateMFATicket(r.Context(), database.CreateMFATicketParams{
+		TokenHash: auth.HashToken(mfaTicket, cfg.tokenHashSecret),
+		ExpiresAt: time.Now().Add(cfg.mfaTicketExpiration),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		errorMessage := "MFA ticket issue"
+		responseError(w, errorMessage, 500)
+		return
+	}
+	res := resBody{
+		MFARequired: true,
+		MFATicket:   mfaTicket,
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		errorMessage := "Cannot marshal response"
+		responseError(w, errorMessage, 500)
+		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+// rehashPassword replaces a hash made with an outdated algorithm or cost. The
+// update is skipped if the password was changed in the meantime.
+func rehashPassword(r *http.Request, cfg *apiConfig, user database.User, password string) {
+	hashedPassword, err := cfg.passwords.Hash(password)
+	if err != nil {
+		log.Printf("Error rehashing password: %s\n", err)
+		return
+	}
+	err = cfg.db.RehashUserPassword(r.Context(), database.RehashUserPasswordParams{
+		ID:                user.ID,
+		OldHashedPassword: user.HashedPassword,
+		NewHashedPassword: hashedPassword,
+	})
+	if err != nil {
+		log.Printf("Error rehashing password: %s\n", err)
+	}
+}
+
+func loginAccountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func loginIPKey(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+// allowLoginAttempt answers with 429 and returns false while the account or
+// the client address is backing off after failed attempts.
+func allowLoginAttempt(w http.ResponseWriter, r *http.Request, cfg *apiConfig, accountKey string) bool {
+	accountRetryAfter, err := cfg.loginAccountLimiter.Check(r.Context(), accountKey)
+	if err != nil {
+		errorMessage := "Cannot check login attempts"
+		responseError(w, errorMessage, 500)
+		return false
+	}
+	ipRetryAfter, err := cfg.loginIPLimiter.Check(r.Context(), loginIPKey(r))
+	if err != nil {
+		errorMessage := "Cannot check login attempts"
+		responseError(w, errorMessage, 500)
+		return false
+	}
+	if retryAfter := max(accountRetryAfter, ipRetryAfter); retryAfter > 0 {
+		responseTooManyRequests(w, retryAfter)
+		return false
+	}
+	return true
+}
+
+func failLoginAttempt(r *http.Request, cfg *apiConfig, accountKey string) {
+	if _, err := cfg.loginAccountLimiter.Fail(r.Context(), accountKey); err != nil {
+		log.Printf("Error recording failed login: %s\n", err)
+	}
+	if _, err := cfg.loginIPLimiter.Fail(r.Context(), loginIPKey(r)); err != nil {
+		log.Printf("Error recording failed login: %s\n", err)
+	}
+}
+
+// succeedLoginAttempt resets only the account. The address keeps its
+// failures until the window expires, or an attacker could sign in to their
+// own account between guesses to clear the per-IP limit.
+func succeedLoginAttempt(r *http.Request, cfg *apiConfig, accountKey string) {
+	if err := cfg.loginAccountLimiter.Succeed(r.Context(), accountKey); err != nil {
+		log.Printf("Error resetting login attempts: %s\n", err)
+	}
+}
+
+// responseLogin starts a new session for an authenticated user and answers
+// with its access and refresh tokens.
+func responseLogin(w http.ResponseWriter, r *http.Request, cfg *apiConfig, user database.User) {
+	type resBody struct {
+		ID           uuid.UUID `json:"id"`
+		CreatedAt    time.Time `json:"created_at"`
+		UpdatedAt    time.Time `json:"updated_at"`
+		Email        string    `json:"email"`
+		Token        string    `json:"token"`
+		RefreshToken string    `json:"refresh_token"`
+		IsChirpyRed  bool      `json:"is_chirpy_red"`
+	}
+	jwtTokenExpiration := 1 * time.Hour
+	jwtToken, err := cfg.jwtKeys.MakeAccessToken(user.ID, user.Role, auth.Scopes, jwtTokenExpiration)
+	if err != nil {
+		errorMessage := "JWT issue"
+		responseError(w, errorMessage, 500)
+		return
+	}
+
+	refreshToken, _, err := createRefreshToken(r, cfg, user.ID, refreshTokenGrant{}, nil)
+	if err != nil {
+		errorMessage := "Refresh token issue"
+		responseError(w, errorMessage, 500)
+		return
+	}
+	res := resBody{
+		ID:           user.ID,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+		Email:        user.Email,
+		Token:        jwtToken,
+		RefreshToken: refreshToken,
+		IsChirpyRed:  user.IsChirpyRed,
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		errorMessage := "Cannot marshal response"
+		responseError(w, errorMessage, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(data)
 }
 
 func refresh(cfg *apiConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		type resBody struct {
-			Token string `json:"token"`
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
 		}
 		refreshToken, err := auth.GetBearerToken(r.Header)
 		if err != nil {
@@ -100,20 +221,47 @@ func refresh(cfg *apiConfig) http.HandlerFunc {
 			responseError(w, errorMessage, 401)
 			return
 		}
-		userID, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
-		if err != nil {
+		refreshTokenHash := auth.HashToken(refreshToken, cfg.tokenHashSecret)
+		oldToken, err := cfg.db.ConsumeRefreshToken(r.Context(), refreshTokenHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			// A token that exists but was already revoked is being replayed,
+			// so every token issued from the same login is treated as stolen.
+			storedToken, err := cfg.db.GetRefreshToken(r.Context(), refreshTokenHash)
+			if err == nil && storedToken.RevokedAt.Valid {
+				if err := cfg.db.RevokeRefreshTokenFamily(r.Context(), storedToken.FamilyID); err != nil {
+					log.Printf("Error revoking refresh token family %s: %s\n", storedToken.FamilyID, err)
+				}
+			}
 			errorMessage := "No valid refresh token found"
 			responseError(w, errorMessage, 401)
 			return
 		}
-		newJwtToken, err := auth.MakeJWT(userID, cfg.jwtSignString, cfg.jwtExpiration)
+		if err != nil {
+			errorMessage := "Refresh token issue"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		newRefreshToken, _, err := createRefreshToken(r, cfg, oldToken.UserID, refreshTokenGrantOf(oldToken), &oldToken)
+		if err != nil {
+			errorMessage := "Refresh token issue"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), oldToken.UserID)
+		if err != nil {
+			errorMessage := "Refresh token issue"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		newJwtToken, err := cfg.jwtKeys.MakeAccessToken(user.ID, user.Role, auth.Scopes, cfg.jwtExpiration)
 		if err != nil {
 			errorMessage := "JWT issue"
 			responseError(w, errorMessage, 500)
 			return
 		}
 		res := resBody{
-			Token: newJwtToken,
+			Token:        newJwtToken,
+			RefreshToken: newRefreshToken,
 		}
 		data, err := json.Marshal(res)
 		if err != nil {
@@ -127,15 +275,22 @@ func refresh(cfg *apiConfig) http.HandlerFunc {
 	}
 }
 
+// revoke takes the refresh token from the Authorization header. Requests
+// with a form body are handled as RFC 7009 revocation requests instead.
 func revoke(cfg *apiConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/x-www-form-urlencoded" {
+			revokeOAuthToken(w, r, cfg)
+			return
+		}
 		refreshToken, err := auth.GetBearerToken(r.Header)
 		if err != nil {
 			errorMessage := "Missing header"
 			responseError(w, errorMessage, 401)
 			return
 		}
-		_, err = cfg.db.RevokeTokenByToken(r.Context(), refreshToken)
+		_, err = cfg.db.RevokeTokenByToken(r.Context(), auth.HashToken(refreshToken, cfg.tokenHashSecret))
 		if err != nil {
 			errorMessage := "No valid refresh token found"
 			responseError(w, errorMessage, 401)
@@ -144,3 +299,52 @@ func revoke(cfg *apiConfig) http.HandlerFunc {
 		w.WriteHeader(204)
 	}
 }
+
+// refreshTokenGrant is what a refresh token family was issued for. Tokens
+// from the first-party login have no client and no scope restriction.
+type refreshTokenGrant struct {
+	ClientID sql.NullString
+	Scopes   []string
+}
+
+func refreshTokenGrantOf(token database.RefreshToken) refreshTokenGrant {
+	return refreshTokenGrant{
+		ClientID: token.ClientID,
+		Scopes:   token.Scopes,
+	}
+}
+
+// createRefreshToken stores a new refresh token and returns it with the ID of
+// its family. A parent continues the parent's family.
+func createRefreshToken(r *http.Request, cfg *apiConfig, userID uuid.UUID, grant refreshTokenGrant, parent *database.RefreshToken) (string, uuid.UUID, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	params := database.CreateRefreshTokenParams{
+		TokenHash:        auth.HashToken(refreshToken, cfg.tokenHashSecret),
+		UserID:           userID,
+		ExpiresAt:        time.Now().Add(cfg.refreshExpiration),
+		FamilyID:         uuid.New(),
+		SessionStartedAt: time.Now(),
+		UserAgent:        r.UserAgent(),
+		IpAddress:        clientIP(r),
+		ClientID:         grant.ClientID,
+		Scopes:           grant.Scopes,
+	}
+	if params.Scopes == nil {
+		params.Scopes = []string{}
+	}
+	if parent != nil {
+		params.FamilyID = parent.FamilyID
+		params.ParentTokenHash = sql.NullString{
+			String: parent.TokenHash,
+			Valid:  true,
+		}
+		params.SessionStartedAt = parent.SessionStartedAt
+	}
+	if _, err = cfg.db.CreateRefreshToken(r.Context(), params); err != nil {
+		return "", uuid.Nil, err
+	}
+	return refreshToken, params.FamilyID, nil
+}
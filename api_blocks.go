@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+// visibility holds the users whose chirps the caller does not see. Blocks
+// work both ways, mutes only hide the muted user from the muter.
+type visibility struct {
+	blocked map[uuid.UUID]bool
+	muted   map[uuid.UUID]bool
+}
+
+func (v visibility) hides(userID uuid.UUID) bool {
+	return v.blocked[userID] || v.muted[userID]
+}
+
+// viewerVisibility loads the blocks and mutes of the signed in caller.
+// Anonymous callers see everything.
+func viewerVisibility(r *http.Request, cfg *apiConfig) (visibility, error) {
+	v := visibility{blocked: map[uuid.UUID]bool{}, muted: map[uuid.UUID]bool{}}
+	userID := principalFromContext(r.Context()).UserID
+	if userID == uuid.Nil {
+		return v, nil
+	}
+	blocked, err := cfg.db.GetBlockRelatedUserIDs(r.Context(), userID)
+	if err != nil {
+		return visibility{}, err
+	}
+	for _, id := range blocked {
+		v.blocked[id] = true
+	}
+	muted, err := cfg.db.GetMutedUserIDs(r.Context(), userID)
+	if err != nil {
+		return visibility{}, err
+	}
+	for _, id := range muted {
+		v.muted[id] = true
+	}
+	return v, nil
+}
+
+// filter drops the chirps and rechirps of hidden users and replaces quoted
+// chirps of hidden users with a tombstone.
+func (v visibility) filter(views []chirpView) []chirpView {
+	filtered := []chirpView{}
+	for _, view := range views {
+		if v.hides(view.UserID) || (view.RechirpedBy != nil && v.hides(view.RechirpedBy.UserID)) {
+			continue
+		}
+		filtered = append(filtered, view)
+	}
+	v.maskQuotes(filtered)
+	return filtered
+}
+
+// maskQuotes replaces quoted chirps of hidden users with a tombstone.
+func (v visibility) maskQuotes(views []chirpView) {
+	for i := range views {
+		quoted := views[i].QuotedChirp
+		if quoted != nil && quoted.chirpView != nil && v.hides(quoted.UserID) {
+			views[i].QuotedChirp = &quotedChirpView{Unavailable: true}
+		}
+	}
+}
+
+// blockedEitherWay reports whether either user blocked the other.
+func blockedEitherWay(ctx context.Context, cfg *apiConfig, userA, userB uuid.UUID) (bool, error) {
+	if userA == uuid.Nil || userB == uuid.Nil || userA == userB {
+		return false, nil
+	}
+	return cfg.db.IsBlockedEitherWay(ctx, database.IsBlockedEitherWayParams{
+		UserA: userA,
+		UserB: userB,
+	})
+}
+
+func blockUser(cfg *apiConfig) http.HandlerFunc {
+	return setBlock(cfg, true)
+}
+
+func unblockUser(cfg *apiConfig) http.HandlerFunc {
+	return setBlock(cfg, false)
+}
+
+// setBlock blocks or unblocks the user for the caller. Blocking also ends
+// follows in both directions and clears both timelines of the other's
+// chirps.
+func setBlock(cfg *apiConfig, block bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		blockerID := principalFromContext(r.Context()).UserID
+		blocked, ok := targetUser(w, r, cfg, blockerID)
+		if !ok {
+			return
+		}
+		if !block {
+			_, err := cfg.db.UnblockUser(r.Context(), database.UnblockUserParams{
+				BlockerID: blockerID,
+				BlockedID: blocked.ID,
+			})
+			if err != nil {
+				errorMessage := "Cannot unblock user"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			w.WriteHeader(204)
+			return
+		}
+		_, err := cfg.db.BlockUser(r.Context(), database.BlockUserParams{
+			BlockerID: blockerID,
+			BlockedID: blocked.ID,
+		})
+		if err != nil {
+			errorMessage := "Cannot block user"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, pair := range [][2]uuid.UUID{{blockerID, blocked.ID}, {blocked.ID, blockerID}} {
+			if _, err := cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+				FollowerID: pair[0],
+				FolloweeID: pair[1],
+			}); err != nil {
+				log.Printf("Error removing follow of blocked user: %s\n", err)
+			}
+			if err := cfg.db.RemoveFromTimeline(r.Context(), database.RemoveFromTimelineParams{
+				FollowerID: pair[0],
+				FolloweeID: pair[1],
+			}); err != nil {
+				log.Printf("Error removing chirps of blocked user from timeline: %s\n", err)
+			}
+		}
+		w.WriteHeader(204)
+	}
+}
+
+func muteUser(cfg *apiConfig) http.HandlerFunc {
+	return setMute(cfg, true)
+}
+
+func unmuteUser(cfg *apiConfig) http.HandlerFunc {
+	return setMute(cfg, false)
+}
+
+// setMute mutes or unmutes the user for the caller. The muted user is not
+// told and can still follow and reply to the caller.
+func setMute(cfg *apiConfig, mute bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		muterID := principalFromContext(r.Context()).UserID
+		muted, ok := targetUser(w, r, cfg, muterID)
+		if !ok {
+			return
+		}
+		var err error
+		if mute {
+			_, err = cfg.db.MuteUser(r.Context(), database.MuteUserParams{
+				MuterID: muterID,
+				MutedID: muted.ID,
+			})
+		} else {
+			_, err = cfg.db.UnmuteUser(r.Context(), database.UnmuteUserParams{
+				MuterID: muterID,
+				MutedID: muted.ID,
+			})
+		}
+		if err != nil {
+			errorMessage := "Cannot update mute"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.WriteHeader(204)
+	}
+}
+
+// targetUser loads the user in the path for block and mute requests. It
+// answers the request itself and returns false when there is none.
+func targetUser(w http.ResponseWriter, r *http.Request, cfg *apiConfig, callerID uuid.UUID) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		errorMessage := "Invalid user ID"
+		responseError(w, errorMessage, 400)
+		return database.User{}, false
+	}
+	if userID == callerID {
+		errorMessage := "You cannot block or mute yourself"
+		responseError(w, errorMessage, 400)
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		errorMessage := "User not found"
+		responseError(w, errorMessage, 404)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func getBlockedUsers(cfg *apiConfig) http.HandlerFunc {
+	return listMyUsers(cfg, func(r *http.Request, userID uuid.UUID, limit, offset int32) ([]database.User, error) {
+		return cfg.db.GetBlockedUsers(r.Context(), database.GetBlockedUsersParams{
+			BlockerID: userID,
+			Limit:     limit,
+			Offset:    offset,
+		})
+	})
+}
+
+func getMutedUsers(cfg *apiConfig) http.HandlerFunc {
+	return listMyUsers(cfg, func(r *http.Request, userID uuid.UUID, limit, offset int32) ([]database.User, error) {
+		return cfg.db.GetMutedUsers(r.Context(), database.GetMutedUsersParams{
+			MuterID: userID,
+			Limit:   limit,
+			Offset:  offset,
+		})
+	})
+}
+
+// listMyUsers answers with one page of the users that list returns for the
+// caller.
+func listMyUsers(cfg *apiConfig, list func(r *http.Request, userID uuid.UUID, limit, offset int32) ([]database.User, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := principalFromContext(r.Context()).UserID
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			responseError(w, err.Error(), 400)
+			return
+		}
+		users, err := list(r, userID, limit, offset)
+		if err != nil {
+			errorMessage := "Cannot retrieve users"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		views := []userProfileView{}
+		for _, user := range users {
+			views = append(views, newUserProfileView(user))
+		}
+		data, err := json.Marshal(views)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
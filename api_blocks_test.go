@@ -0,0 +1,79 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestVisibilityFilter(t *testing.T) {
+	visible := uuid.New()
+	blocked := uuid.New()
+	muted := uuid.New()
+	vis := visibility{
+		blocked: map[uuid.UUID]bool{blocked: true},
+		muted:   map[uuid.UUID]bool{muted: true},
+	}
+
+	cases := []struct {
+		view     chirpView
+		expected bool
+	}{
+		{view: chirpView{UserID: visible}, expected: true},
+		{view: chirpView{UserID: blocked}, expected: false},
+		{view: chirpView{UserID: muted}, expected: false},
+		{view: chirpView{UserID: visible, RechirpedBy: &rechirpView{UserID: visible, RechirpedAt: time.Now()}}, expected: true},
+		{view: chirpView{UserID: visible, RechirpedBy: &rechirpView{UserID: blocked, RechirpedAt: time.Now()}}, expected: false},
+		{view: chirpView{UserID: visible, RechirpedBy: &rechirpView{UserID: muted, RechirpedAt: time.Now()}}, expected: false},
+	}
+
+	for i, c := range cases {
+		c.view.ID = uuid.New()
+		filtered := vis.filter([]chirpView{c.view})
+		kept := slices.ContainsFunc(filtered, func(view chirpView) bool { return view.ID == c.view.ID })
+		if kept != c.expected {
+			t.Errorf("Test nr: %d: Expected kept %v, got %v", i, c.expected, kept)
+		}
+	}
+}
+
+func TestVisibilityMaskQuotes(t *testing.T) {
+	visible := uuid.New()
+	blocked := uuid.New()
+	muted := uuid.New()
+	vis := visibility{
+		blocked: map[uuid.UUID]bool{blocked: true},
+		muted:   map[uuid.UUID]bool{muted: true},
+	}
+
+	cases := []struct {
+		quoted      *quotedChirpView
+		unavailable bool
+	}{
+		{quoted: nil, unavailable: false},
+		{quoted: &quotedChirpView{Deleted: true}, unavailable: false},
+		{quoted: &quotedChirpView{chirpView: &chirpView{UserID: visible}}, unavailable: false},
+		{quoted: &quotedChirpView{chirpView: &chirpView{UserID: blocked}}, unavailable: true},
+		{quoted: &quotedChirpView{chirpView: &chirpView{UserID: muted}}, unavailable: true},
+	}
+
+	for i, c := range cases {
+		views := []chirpView{{ID: uuid.New(), UserID: visible, QuotedChirp: c.quoted}}
+		vis.maskQuotes(views)
+		quoted := views[0].QuotedChirp
+		if c.quoted == nil {
+			if quoted != nil {
+				t.Errorf("Test nr: %d: Expected no quoted chirp, got %+v", i, quoted)
+			}
+			continue
+		}
+		if quoted.Unavailable != c.unavailable {
+			t.Errorf("Test nr: %d: Expected unavailable %v, got %v", i, c.unavailable, quoted.Unavailable)
+		}
+		if c.unavailable && quoted.chirpView != nil {
+			t.Errorf("Test nr: %d: Expected the original to be left out", i)
+		}
+	}
+}
@@ -1,21 +1,121 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"sort"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hrncacz/go-chirpy/internal/auth"
 	"github.com/hrncacz/go-chirpy/internal/database"
 )
 
+// chirpMaxLength is the longest chirp body in bytes.
+const chirpMaxLength = 140
+
+// chirpView is a chirp as the API returns it.
+type chirpView struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Edited    bool       `json:"edited"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	// InReplyToID is null for chirps that start a conversation and for
+	// replies whose parent was deleted.
+	InReplyToID *uuid.UUID `json:"in_reply_to_id"`
+	RootID      *uuid.UUID `json:"root_id"`
+	ReplyCount  int32      `json:"reply_count"`
+	LikeCount   int32      `json:"like_count"`
+	// LikedByMe is only set for signed in callers.
+	LikedByMe    *bool      `json:"liked_by_me,omitempty"`
+	RechirpCount int32      `json:"rechirp_count"`
+	QuoteCount   int32      `json:"quote_count"`
+	QuoteOfID    *uuid.UUID `json:"quote_of_id"`
+	// QuotedChirp is the original a quote chirp embeds, filled in by
+	// embedQuotedChirps.
+	QuotedChirp *quotedChirpView `json:"quoted_chirp,omitempty"`
+	// RechirpedBy is set when the chirp is listed as someone's rechirp.
+	RechirpedBy *rechirpView `json:"rechirped_by,omitempty"`
+}
+
+// listedAt is when the chirp entered a listing: when it was posted, or for a
+// rechirp when it was rechirped.
+func (v chirpView) listedAt() time.Time {
+	if v.RechirpedBy != nil {
+		return v.RechirpedBy.RechirpedAt
+	}
+	return v.CreatedAt
+}
+
+func newChirpView(chirp database.Chirp) chirpView {
+	view := chirpView{
+		ID:           chirp.ID,
+		CreatedAt:    chirp.CreatedAt,
+		UpdatedAt:    chirp.UpdatedAt,
+		Body:         chirp.Body,
+		UserID:       chirp.UserID,
+		Edited:       chirp.EditedAt.Valid,
+		ReplyCount:   chirp.ReplyCount,
+		LikeCount:    chirp.LikeCount,
+		RechirpCount: chirp.RechirpCount,
+		QuoteCount:   chirp.QuoteCount,
+	}
+	if chirp.EditedAt.Valid {
+		view.EditedAt = &chirp.EditedAt.Time
+	}
+	if chirp.InReplyToID.Valid {
+		view.InReplyToID = &chirp.InReplyToID.UUID
+	}
+	if chirp.RootID.Valid {
+		view.RootID = &chirp.RootID.UUID
+	}
+	if chirp.QuoteOfID.Valid {
+		view.QuoteOfID = &chirp.QuoteOfID.UUID
+	}
+	return view
+}
+
+func newChirpViews(chirps []database.Chirp) []chirpView {
+	views := []chirpView{}
+	for _, chirp := range chirps {
+		views = append(views, newChirpView(chirp))
+	}
+	return views
+}
+
+// prepareChirpViews embeds quoted chirps, drops the chirps the caller has
+// blocked or muted and marks the ones the caller liked.
+func prepareChirpViews(r *http.Request, cfg *apiConfig, views []chirpView) ([]chirpView, error) {
+	if err := embedQuotedChirps(r.Context(), cfg, views); err != nil {
+		return nil, err
+	}
+	vis, err := viewerVisibility(r, cfg)
+	if err != nil {
+		return nil, err
+	}
+	views = vis.filter(views)
+	if err := setLikedByMe(r, cfg, views); err != nil {
+		return nil, err
+	}
+	return views, nil
+}
+
 func getChirpsAll(cfg *apiConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authorID := r.URL.Query().Get("author_id")
+		author := r.URL.Query().Get("author")
+		mentions := r.URL.Query().Get("mentions")
 		sortChirps := r.URL.Query().Get("sort")
 		var chirps []database.Chirp
+		// Listing a user's chirps includes what the user rechirped.
+		var rechirpsBy uuid.UUID
 		var err error
 		if len(authorID) > 0 {
 			authorUUID, err := uuid.Parse(authorID)
@@ -32,6 +132,34 @@ func getChirpsAll(cfg *apiConfig) http.HandlerFunc {
 				responseError(w, errorMessage, 401)
 				return
 			}
+			rechirpsBy = authorUUID
+		} else if len(author) > 0 || len(mentions) > 0 {
+			h := author
+			if len(h) == 0 {
+				h = mentions
+			}
+			user, _, err := resolveHandle(r.Context(), cfg, h)
+			if errors.Is(err, sql.ErrNoRows) {
+				errorMessage := "User not found"
+				responseError(w, errorMessage, 404)
+				return
+			}
+			if err != nil {
+				errorMessage := "Cannot retrieve chirps"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			if len(author) > 0 {
+				chirps, err = cfg.db.GetChirpsAllByUserID(r.Context(), user.ID)
+				rechirpsBy = user.ID
+			} else {
+				chirps, err = cfg.db.GetChirpsMentioningUser(r.Context(), user.ID)
+			}
+			if err != nil {
+				errorMessage := "Cannot retrieve chirps"
+				responseError(w, errorMessage, 500)
+				return
+			}
 		} else {
 			chirps, err = cfg.db.GetChirpsAll(r.Context())
 			if err != nil {
@@ -41,10 +169,29 @@ func getChirpsAll(cfg *apiConfig) http.HandlerFunc {
 				return
 			}
 		}
+		views := newChirpViews(chirps)
+		if rechirpsBy != uuid.Nil {
+			rechirps, err := cfg.db.GetRechirpsByUserID(r.Context(), rechirpsBy)
+			if err != nil {
+				errorMessage := "Cannot retrieve chirps"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			for _, row := range rechirps {
+				views = append(views, newRechirpView(row, rechirpsBy))
+			}
+			sort.SliceStable(views, func(a, b int) bool { return views[a].listedAt().Before(views[b].listedAt()) })
+		}
 		if sortChirps == "desc" {
-			sort.Slice(chirps, func(a, b int) bool { return chirps[a].CreatedAt.After(chirps[b].CreatedAt) })
+			sort.SliceStable(views, func(a, b int) bool { return views[a].listedAt().After(views[b].listedAt()) })
 		}
-		data, err := json.Marshal(chirps)
+		views, err = prepareChirpViews(r, cfg, views)
+		if err != nil {
+			errorMessage := "Cannot retrieve chirps"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		data, err := json.Marshal(views)
 		if err != nil {
 			errorMessage := "Cannot marshal response"
 			responseError(w, errorMessage, 500)
@@ -71,7 +218,31 @@ func getChirpsOne(cfg *apiConfig) http.HandlerFunc {
 			responseError(w, errorMessage, 404)
 			return
 		}
-		data, err := json.Marshal(chirps)
+		vis, err := viewerVisibility(r, cfg)
+		if err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		// Muted users' chirps are still shown when asked for directly.
+		if vis.blocked[chirps.UserID] {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		views := []chirpView{newChirpView(chirps)}
+		if err := embedQuotedChirps(r.Context(), cfg, views); err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		vis.maskQuotes(views)
+		if err := setLikedByMe(r, cfg, views); err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		data, err := json.Marshal(views[0])
 		if err != nil {
 			errorMessage := "Cannot marshal response"
 			responseError(w, errorMessage, 500)
@@ -87,19 +258,21 @@ func getChirpsOne(cfg *apiConfig) http.HandlerFunc {
 func createChirp(cfg *apiConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		type reqBody struct {
-			Body string `json:"body"`
+			Body        string     `json:"body"`
+			InReplyToID *uuid.UUID `json:"in_reply_to_id"`
+			QuoteOfID   *uuid.UUID `json:"quote_of_id"`
 		}
 
-		token, err := auth.GetBearerToken(r.Header)
+		userID := principalFromContext(r.Context()).UserID
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
 		if err != nil {
 			errorMessage := "Unauthorized"
 			responseError(w, errorMessage, 401)
 			return
 		}
-		userID, err := auth.ValidateJWT(token, cfg.jwtSignString)
-		if err != nil {
-			errorMessage := "Unauthorized"
-			responseError(w, errorMessage, 401)
+		if !emailVerified(cfg, user) {
+			errorMessage := "Email address is not verified"
+			responseError(w, errorMessage, 403)
 			return
 		}
 
@@ -112,23 +285,77 @@ func createChirp(cfg *apiConfig) http.HandlerFunc {
 			responseError(w, errorMessage, http.StatusBadRequest)
 			return
 		}
-		if len(req.Body) > 140 {
+		if len(req.Body) > chirpMaxLength {
 			errorMessage := "Chirp is too long"
 			responseError(w, errorMessage, 400)
 			return
 		}
 
-		chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
+		params := database.CreateChirpParams{
 			Body:   req.Body,
 			UserID: userID,
-		})
+		}
+		if req.InReplyToID != nil {
+			parent, err := cfg.db.GetChirpsOne(r.Context(), *req.InReplyToID)
+			if err != nil {
+				errorMessage := "Chirp being replied to was not found"
+				responseError(w, errorMessage, 404)
+				return
+			}
+			blocked, err := blockedEitherWay(r.Context(), cfg, userID, parent.UserID)
+			if err != nil {
+				errorMessage := "Cannot create chirp"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			if blocked {
+				errorMessage := "You cannot reply to this chirp"
+				responseError(w, errorMessage, 403)
+				return
+			}
+			params.InReplyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+			params.RootID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+			if parent.RootID.Valid {
+				params.RootID = parent.RootID
+			}
+		}
+		if req.QuoteOfID != nil {
+			quoted, err := cfg.db.GetChirpsOne(r.Context(), *req.QuoteOfID)
+			if err != nil {
+				errorMessage := "Quoted chirp was not found"
+				responseError(w, errorMessage, 404)
+				return
+			}
+			blocked, err := blockedEitherWay(r.Context(), cfg, userID, quoted.UserID)
+			if err != nil {
+				errorMessage := "Cannot create chirp"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			if blocked {
+				errorMessage := "You cannot quote this chirp"
+				responseError(w, errorMessage, 403)
+				return
+			}
+			params.QuoteOfID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+		}
+
+		chirp, err := cfg.db.CreateChirp(r.Context(), params)
 		if err != nil {
 			fmt.Println(err)
 			errorMessage := "Cannot create chirp"
 			responseError(w, errorMessage, 500)
 			return
 		}
-		data, err := json.Marshal(chirp)
+		if err := cfg.db.FanOutChirp(r.Context(), chirp.ID); err != nil {
+			log.Printf("Error adding chirp to timelines: %s\n", err)
+		}
+		recordMentions(r.Context(), cfg, chirp)
+		views := []chirpView{newChirpView(chirp)}
+		if err := embedQuotedChirps(r.Context(), cfg, views); err != nil {
+			log.Printf("Error embedding quoted chirp: %s\n", err)
+		}
+		data, err := json.Marshal(views[0])
 		if err != nil {
 			errorMessage := "Cannot marshal response"
 			responseError(w, errorMessage, 500)
@@ -143,19 +370,60 @@ func createChirp(cfg *apiConfig) http.HandlerFunc {
 
 func deleteChirp(cfg *apiConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		token, err := auth.GetBearerToken(r.Header)
+		p := principalFromContext(r.Context())
+		chirpIDString := r.PathValue("chirpID")
+		chirpID, err := uuid.Parse(chirpIDString)
 		if err != nil {
-			errorMessage := "Unauthorized"
-			responseError(w, errorMessage, 401)
+			errorMessage := "Invalid chirp ID"
+			responseError(w, errorMessage, 400)
 			return
 		}
-		userID, err := auth.ValidateJWT(token, cfg.jwtSignString)
+		chirps, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
 		if err != nil {
-			errorMessage := "Unauthorized"
-			responseError(w, errorMessage, 401)
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		// Moderators and admins can remove chirps of other users.
+		if p.UserID != chirps.UserID && !p.hasRole(auth.RoleModerator, auth.RoleAdmin) {
+			errorMessage := "user is not owner of chirp"
+			responseError(w, errorMessage, 403)
+			return
+		}
+		if err = cfg.db.DeleteChirpById(r.Context(), database.DeleteChirpByIdParams{
+			ID:     chirpID,
+			UserID: chirps.UserID,
+		}); err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not deleted: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
 			return
 		}
+		w.WriteHeader(204)
+	}
+}
+
+// chirpEditPolicy limits how long after posting and how often a chirp can
+// be edited.
+type chirpEditPolicy struct {
+	Window   time.Duration
+	MaxEdits int32
+}
+
+func (cfg *apiConfig) chirpEditPolicyFor(user database.User) chirpEditPolicy {
+	if user.IsChirpyRed {
+		return cfg.chirpRedEdits
+	}
+	return cfg.chirpEdits
+}
 
+// editChirp replaces the body of the caller's chirp and keeps the previous
+// version in chirp_revisions.
+func editChirp(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reqBody struct {
+			Body string `json:"body"`
+		}
+		userID := principalFromContext(r.Context()).UserID
 		chirpIDString := r.PathValue("chirpID")
 		chirpID, err := uuid.Parse(chirpIDString)
 		if err != nil {
@@ -163,25 +431,175 @@ func deleteChirp(cfg *apiConfig) http.HandlerFunc {
 			responseError(w, errorMessage, 400)
 			return
 		}
-		chirps, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
+		decoder := json.NewDecoder(r.Body)
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if len(req.Body) > chirpMaxLength {
+			errorMessage := "Chirp is too long"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		chirp, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
 		if err != nil {
 			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
 			responseError(w, errorMessage, 404)
 			return
 		}
-		if userID != chirps.UserID {
+		if chirp.UserID != userID {
 			errorMessage := "user is not owner of chirp"
 			responseError(w, errorMessage, 403)
 			return
 		}
-		if err = cfg.db.DeleteChirpById(r.Context(), database.DeleteChirpByIdParams{
-			ID:     chirpID,
-			UserID: userID,
-		}); err != nil {
-			errorMessage := fmt.Sprintf("Chirp was not deleted: %s", chirpIDString)
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			errorMessage := "Unauthorized"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		policy := cfg.chirpEditPolicyFor(user)
+		if time.Since(chirp.CreatedAt) > policy.Window {
+			errorMessage := fmt.Sprintf("Chirps can only be edited within %s of posting", policy.Window)
+			responseError(w, errorMessage, 403)
+			return
+		}
+		if chirp.EditCount >= policy.MaxEdits {
+			errorMessage := fmt.Sprintf("Chirps can be edited at most %d times", policy.MaxEdits)
+			responseError(w, errorMessage, 403)
+			return
+		}
+		if req.Body == chirp.Body {
+			data, err := json.Marshal(newChirpView(chirp))
+			if err != nil {
+				errorMessage := "Cannot marshal response"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(200)
+			w.Write(data)
+			return
+		}
+		versionCreatedAt := chirp.CreatedAt
+		if chirp.EditedAt.Valid {
+			versionCreatedAt = chirp.EditedAt.Time
+		}
+		// The edit and the archived version are stored together so the
+		// history never misses a version. The edit count guards against a
+		// concurrent edit replacing the version we are about to archive.
+		var edited database.Chirp
+		err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			edited, err = q.EditChirp(r.Context(), database.EditChirpParams{
+				ID:        chirp.ID,
+				Body:      req.Body,
+				EditCount: chirp.EditCount,
+			})
+			if err != nil {
+				return err
+			}
+			return q.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+				ChirpID:   chirp.ID,
+				Version:   chirp.EditCount,
+				Body:      chirp.Body,
+				CreatedAt: versionCreatedAt,
+			})
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			errorMessage := "Chirp was edited concurrently, please retry"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		if err != nil {
+			errorMessage := "Cannot edit chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if err := cfg.db.DeleteChirpMentions(r.Context(), chirp.ID); err != nil {
+			log.Printf("Error deleting chirp mentions: %s\n", err)
+		}
+		recordMentions(r.Context(), cfg, edited)
+		data, err := json.Marshal(newChirpView(edited))
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// getChirpHistory lists every version of a chirp, oldest first, ending with
+// the current one.
+func getChirpHistory(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type revision struct {
+			Version    int32      `json:"version"`
+			Body       string     `json:"body"`
+			CreatedAt  time.Time  `json:"created_at"`
+			ReplacedAt *time.Time `json:"replaced_at"`
+		}
+		chirpIDString := r.PathValue("chirpID")
+		chirpID, err := uuid.Parse(chirpIDString)
+		if err != nil {
+			errorMessage := "Invalid chirp ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		chirp, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
 			responseError(w, errorMessage, 404)
 			return
 		}
-		w.WriteHeader(204)
+		blocked, err := blockedEitherWay(r.Context(), cfg, principalFromContext(r.Context()).UserID, chirp.UserID)
+		if err != nil {
+			errorMessage := "Cannot retrieve chirp history"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if blocked {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		rows, err := cfg.db.GetChirpRevisions(r.Context(), chirp.ID)
+		if err != nil {
+			errorMessage := "Cannot retrieve chirp history"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := []revision{}
+		for _, row := range rows {
+			res = append(res, revision{
+				Version:    row.Version,
+				Body:       row.Body,
+				CreatedAt:  row.CreatedAt,
+				ReplacedAt: &row.ReplacedAt,
+			})
+		}
+		current := revision{
+			Version:   chirp.EditCount,
+			Body:      chirp.Body,
+			CreatedAt: chirp.CreatedAt,
+		}
+		if chirp.EditedAt.Valid {
+			current.CreatedAt = chirp.EditedAt.Time
+		}
+		res = append(res, current)
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
 	}
 }
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/auth"
+	"github.com/hrncacz/go-chirpy/internal/database"
+	"github.com/hrncacz/go-chirpy/internal/mailer"
+	"github.com/lib/pq"
+)
+
+// validateEmail accepts a bare address such as "user@example.com".
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return err
+	}
+	if address.Address != email {
+		return errors.New("email must be a bare address")
+	}
+	return nil
+}
+
+// sendEmailVerification replaces any outstanding verification token of the
+// user with a new one for the given address and emails it.
+func sendEmailVerification(ctx context.Context, cfg *apiConfig, userID uuid.UUID, email string) error {
+	if err := cfg.db.InvalidateUserEmailVerificationTokens(ctx, userID); err != nil {
+		return err
+	}
+	verificationToken, err := auth.MakeToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(verificationToken, cfg.tokenHashSecret),
+		ExpiresAt: time.Now().Add(cfg.emailVerificationExpiration),
+		Email:     email,
+		UserID:    userID,
+	})
+	if err != nil {
+		return err
+	}
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf(`Please confirm that this address belongs to your Chirpy account by opening this link within %s:
+%s/api/verify-email?token=%s
+
+If it was not you, you can ignore this email.
+`, cfg.emailVerificationExpiration, cfg.baseURL, url.QueryEscape(verificationToken)),
+	}
+	go func() {
+		if err := cfg.mailer.Send(context.Background(), msg); err != nil {
+			log.Printf("Error sending verification email: %s\n", err)
+		}
+	}()
+	return nil
+}
+
+// emailVerified reports whether the user may use features that need a
+// verified address. New accounts get a grace period to confirm it.
+func emailVerified(cfg *apiConfig, user database.User) bool {
+	if user.EmailVerifiedAt.Valid {
+		return true
+	}
+	return time.Since(user.CreatedAt) < cfg.emailVerificationGrace
+}
+
+func verifyEmail(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type resBody struct {
+			ID              uuid.UUID `json:"id"`
+			Email           string    `json:"email"`
+			EmailVerifiedAt time.Time `json:"email_verified_at"`
+		}
+		verificationToken := r.URL.Query().Get("token")
+		if len(verificationToken) == 0 {
+			errorMessage := "Missing token"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		// The token is only used up when the address is actually verified,
+		// so a failed update leaves it valid for another try.
+		var user database.User
+		var tokenErr error
+		err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+			token, err := q.ConsumeEmailVerificationToken(r.Context(), auth.HashToken(verificationToken, cfg.tokenHashSecret))
+			if err != nil {
+				tokenErr = err
+				return err
+			}
+			user, err = q.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+				ID:    token.UserID,
+				Email: token.Email,
+			})
+			return err
+		})
+		if errors.Is(tokenErr, sql.ErrNoRows) {
+			errorMessage := "Invalid or expired verification token"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			errorMessage := "Email address is no longer pending verification"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			errorMessage := "Email address is already in use by another account"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		if err != nil {
+			errorMessage := "Cannot verify email"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := resBody{
+			ID:              user.ID,
+			Email:           user.Email,
+			EmailVerifiedAt: user.EmailVerifiedAt.Time,
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+func resendEmailVerification(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		email := user.Email
+		if user.PendingEmail.Valid {
+			email = user.PendingEmail.String
+		} else if user.EmailVerifiedAt.Valid {
+			errorMessage := "Email address is already verified"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		if err := sendEmailVerification(r.Context(), cfg, user.ID, email); err != nil {
+			errorMessage := "Cannot send verification email"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.WriteHeader(202)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+// timelineBackfill is how many of the newest chirps of a followed account
+// are copied into the follower's timeline when following it.
+const timelineBackfill = 100
+
+func followUser(cfg *apiConfig) http.HandlerFunc {
+	return setFollow(cfg, true)
+}
+
+func unfollowUser(cfg *apiConfig) http.HandlerFunc {
+	return setFollow(cfg, false)
+}
+
+// setFollow follows or unfollows the user for the caller, keeps the caller's
+// timeline in step and answers with the user's profile. Repeating either
+// request changes nothing.
+func setFollow(cfg *apiConfig, follow bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		followerID := principalFromContext(r.Context()).UserID
+		followeeID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			errorMessage := "Invalid user ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if followeeID == followerID {
+			errorMessage := "You cannot follow yourself"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		followee, err := cfg.db.GetUserByID(r.Context(), followeeID)
+		if err != nil || (follow && followee.DeletionScheduledAt.Valid) {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		if follow {
+			blocked, err := blockedEitherWay(r.Context(), cfg, followerID, followee.ID)
+			if err != nil {
+				errorMessage := "Cannot follow user"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			if blocked {
+				errorMessage := "You cannot follow this user"
+				responseError(w, errorMessage, 403)
+				return
+			}
+			changed, err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+				FollowerID: followerID,
+				FolloweeID: followee.ID,
+			})
+			if err != nil {
+				errorMessage := "Cannot follow user"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			if changed > 0 {
+				err = cfg.db.BackfillTimeline(r.Context(), database.BackfillTimelineParams{
+					FollowerID: followerID,
+					FolloweeID: followee.ID,
+					MaxEntries: timelineBackfill,
+				})
+				if err != nil {
+					log.Printf("Error backfilling timeline: %s\n", err)
+				}
+			}
+		} else {
+			changed, err := cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+				FollowerID: followerID,
+				FolloweeID: followee.ID,
+			})
+			if err != nil {
+				errorMessage := "Cannot unfollow user"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			if changed > 0 {
+				err = cfg.db.RemoveFromTimeline(r.Context(), database.RemoveFromTimelineParams{
+					FollowerID: followerID,
+					FolloweeID: followee.ID,
+				})
+				if err != nil {
+					log.Printf("Error removing chirps from timeline: %s\n", err)
+				}
+			}
+		}
+		// Read the user again for the counts the trigger left behind.
+		followee, err = cfg.db.GetUserByID(r.Context(), followee.ID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		data, err := json.Marshal(newUserProfileView(followee))
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+func getFollowers(cfg *apiConfig) http.HandlerFunc {
+	return listFollows(cfg, func(r *http.Request, userID uuid.UUID, limit, offset int32) ([]database.User, error) {
+		return cfg.db.GetFollowers(r.Context(), database.GetFollowersParams{
+			FolloweeID: userID,
+			Limit:      limit,
+			Offset:     offset,
+		})
+	})
+}
+
+func getFollowing(cfg *apiConfig) http.HandlerFunc {
+	return listFollows(cfg, func(r *http.Request, userID uuid.UUID, limit, offset int32) ([]database.User, error) {
+		return cfg.db.GetFollowing(r.Context(), database.GetFollowingParams{
+			FollowerID: userID,
+			Limit:      limit,
+			Offset:     offset,
+		})
+	})
+}
+
+// listFollows answers with one page of the users that list returns for the
+// user in the path.
+func listFollows(cfg *apiConfig, list func(r *http.Request, userID uuid.UUID, limit, offset int32) ([]database.User, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			errorMessage := "Invalid user ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			responseError(w, err.Error(), 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil || user.DeletionScheduledAt.Valid {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		users, err := list(r, user.ID, limit, offset)
+		if err != nil {
+			errorMessage := "Cannot retrieve users"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		views := []userProfileView{}
+		for _, user := range users {
+			views = append(views, newUserProfileView(user))
+		}
+		data, err := json.Marshal(views)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/database"
+	"github.com/hrncacz/go-chirpy/internal/handle"
+	"github.com/lib/pq"
+)
+
+// maxMentions caps how many mentions of a single chirp are recorded.
+const maxMentions = 10
+
+var errHandleTaken = errors.New("handle is already taken")
+
+// resolveHandle finds the user who owns the handle, with or without its
+// leading @. During the redirect period a changed handle still resolves to
+// its previous owner and redirected is true.
+func resolveHandle(ctx context.Context, cfg *apiConfig, h string) (user database.User, redirected bool, err error) {
+	h = handle.Normalize(h)
+	user, err = cfg.db.GetUserByHandle(ctx, h)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return user, false, err
+	}
+	redirect, err := cfg.db.GetHandleRedirect(ctx, h)
+	if err != nil {
+		return database.User{}, false, err
+	}
+	user, err = cfg.db.GetUserByID(ctx, redirect.UserID)
+	return user, true, err
+}
+
+// checkHandleAvailable reports errHandleTaken when another user owns the
+// handle or still holds it through a redirect.
+func checkHandleAvailable(ctx context.Context, q *database.Queries, userID uuid.UUID, h string) error {
+	owner, err := q.GetUserByHandle(ctx, h)
+	if err == nil && owner.ID != userID {
+		return errHandleTaken
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	redirect, err := q.GetHandleRedirect(ctx, h)
+	if err == nil && redirect.UserID != userID {
+		return errHandleTaken
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// setHandle gives the user a new handle. The old one keeps redirecting to
+// the user for cfg.handleRedirectPeriod unless only its case changed. Run it
+// in a transaction so a failed redirect does not free the old handle.
+func setHandle(ctx context.Context, cfg *apiConfig, q *database.Queries, user database.User, h string) (database.User, error) {
+	if user.Handle.String == h {
+		return user, nil
+	}
+	updated, err := q.SetUserHandle(ctx, database.SetUserHandleParams{
+		ID:     user.ID,
+		Handle: sql.NullString{String: h, Valid: true},
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return database.User{}, errHandleTaken
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+	// Another user's handle change may have left a redirect for the handle
+	// since it was checked, so check again now that it is ours.
+	if err := checkHandleAvailable(ctx, q, user.ID, h); err != nil {
+		return database.User{}, err
+	}
+	// Taking back an old handle ends its redirect.
+	if err := q.DeleteHandleRedirect(ctx, h); err != nil {
+		return database.User{}, err
+	}
+	if user.Handle.Valid && handle.Normalize(user.Handle.String) != handle.Normalize(h) {
+		err = q.CreateHandleRedirect(ctx, database.CreateHandleRedirectParams{
+			OldHandle: user.Handle.String,
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(cfg.handleRedirectPeriod),
+		})
+		if err != nil {
+			return database.User{}, err
+		}
+	}
+	return updated, nil
+}
+
+// recordMentions links the chirp to the users it mentions. Unknown handles
+// and users who blocked the author or were blocked by them are ignored.
+func recordMentions(ctx context.Context, cfg *apiConfig, chirp database.Chirp) {
+	mentions := handle.Mentions(chirp.Body)
+	if len(mentions) > maxMentions {
+		mentions = mentions[:maxMentions]
+	}
+	for _, mention := range mentions {
+		user, _, err := resolveHandle(ctx, cfg, mention)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error resolving mention @%s: %s\n", mention, err)
+			continue
+		}
+		blocked, err := blockedEitherWay(ctx, cfg, chirp.UserID, user.ID)
+		if err != nil {
+			log.Printf("Error checking blocks for mention @%s: %s\n", mention, err)
+			continue
+		}
+		if blocked {
+			continue
+		}
+		err = cfg.db.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+			ChirpID: chirp.ID,
+			UserID:  user.ID,
+		})
+		if err != nil {
+			log.Printf("Error recording mention @%s: %s\n", mention, err)
+		}
+	}
+}
+
+// getUserByHandle answers with the public profile. A handle that has been
+// changed recently redirects to the current one.
+func getUserByHandle(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, redirected, err := resolveHandle(r.Context(), cfg, r.PathValue("handle"))
+		if err != nil || user.DeletionScheduledAt.Valid {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		if redirected && user.Handle.Valid {
+			// Not permanent: the old handle is free again after the redirect
+			// period.
+			http.Redirect(w, r, "/api/users/by-handle/"+url.PathEscape(user.Handle.String), http.StatusFound)
+			return
+		}
+		data, err := json.Marshal(newUserProfileView(user))
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// responseHandleError answers with 400 for invalid handles and 409 for taken
+// ones. It returns false if err is nil.
+func responseHandleError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, errHandleTaken):
+		errorMessage := "Handle is already taken"
+		responseError(w, errorMessage, 409)
+	case errors.Is(err, handle.ErrLength), errors.Is(err, handle.ErrCharset), errors.Is(err, handle.ErrNoLetter), errors.Is(err, handle.ErrReserved):
+		errorMessage := "Invalid handle: " + strings.TrimPrefix(err.Error(), "handle ")
+		responseError(w, errorMessage, 400)
+	default:
+		log.Printf("Error checking handle: %s\n", err)
+		errorMessage := "Cannot check handle"
+		responseError(w, errorMessage, 500)
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hrncacz/go-chirpy/internal/auth"
+)
+
+// introspectionResponse is the RFC 7662 answer. Inactive tokens only report
+// active false.
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// introspect tells a resource server whether a token is still good. Callers
+// authenticate as a confidential OAuth client so they never need our signing
+// keys. token_type is access_token, refresh_token or personal_access_token.
+func introspect(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			responseOAuthError(w, 400, "invalid_request", "Cannot parse form")
+			return
+		}
+		client, ok := authenticateOAuthClient(r, cfg)
+		if !ok || !client.SecretHash.Valid {
+			if _, _, basic := r.BasicAuth(); basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+			}
+			responseOAuthError(w, 401, "invalid_client", "Client authentication failed")
+			return
+		}
+		token := r.PostFormValue("token")
+		if len(token) == 0 {
+			responseOAuthError(w, 400, "invalid_request", "Missing token")
+			return
+		}
+		var res introspectionResponse
+		var err error
+		switch {
+		case auth.BearerTokenKind(token) == auth.TokenKindPersonalAccess:
+			res, err = introspectPersonalAccessToken(r, cfg, token)
+		case strings.Contains(token, "."):
+			res = introspectAccessToken(r, cfg, token)
+		default:
+			res, err = introspectRefreshToken(r, cfg, token)
+		}
+		if err != nil {
+			log.Printf("Error introspecting token: %s\n", err)
+			responseOAuthError(w, 500, "server_error", "Cannot check token")
+			return
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			log.Printf("Error marshalling JSON: %s\n", err)
+			w.WriteHeader(500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// introspectAccessToken reports any token that fails validation, including
+// when the denylist cannot be read, as inactive.
+func introspectAccessToken(r *http.Request, cfg *apiConfig, token string) introspectionResponse {
+	claims, err := validateAccessToken(r.Context(), cfg, token)
+	if err != nil {
+		return introspectionResponse{}
+	}
+	res := introspectionResponse{
+		Active:    true,
+		TokenType: "access_token",
+		Subject:   claims.Subject,
+		ClientID:  claims.ClientID,
+		Scope:     strings.Join(claims.GetScopes(), " "),
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return res
+}
+
+func introspectRefreshToken(r *http.Request, cfg *apiConfig, token string) (introspectionResponse, error) {
+	refreshToken, err := cfg.db.GetRefreshToken(r.Context(), auth.HashToken(token, cfg.tokenHashSecret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return introspectionResponse{}, nil
+	}
+	if err != nil {
+		return introspectionResponse{}, err
+	}
+	if refreshToken.RevokedAt.Valid || refreshToken.ExpiresAt.Before(time.Now()) {
+		return introspectionResponse{}, nil
+	}
+	// First-party refresh tokens are not limited to scopes.
+	scopes := refreshToken.Scopes
+	if !refreshToken.ClientID.Valid {
+		scopes = auth.Scopes
+	}
+	return introspectionResponse{
+		Active:    true,
+		TokenType: "refresh_token",
+		Subject:   refreshToken.UserID.String(),
+		ClientID:  refreshToken.ClientID.String,
+		Scope:     strings.Join(scopes, " "),
+		ExpiresAt: refreshToken.ExpiresAt.Unix(),
+		IssuedAt:  refreshToken.CreatedAt.Unix(),
+	}, nil
+}
+
+func introspectPersonalAccessToken(r *http.Request, cfg *apiConfig, token string) (introspectionResponse, error) {
+	personalAccessToken, err := cfg.db.GetPersonalAccessToken(r.Context(), auth.HashToken(token, cfg.tokenHashSecret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return introspectionResponse{}, nil
+	}
+	if err != nil {
+		return introspectionResponse{}, err
+	}
+	return introspectionResponse{
+		Active:    true,
+		TokenType: "personal_access_token",
+		Subject:   personalAccessToken.UserID.String(),
+		Scope:     strings.Join(personalAccessToken.Scopes, " "),
+		ExpiresAt: personalAccessToken.ExpiresAt.Unix(),
+		IssuedAt:  personalAccessToken.CreatedAt.Unix(),
+	}, nil
+}
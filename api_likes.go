@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+// setLikedByMe fills in LikedByMe when the request has a signed in caller.
+// Anonymous callers get the views unchanged.
+func setLikedByMe(r *http.Request, cfg *apiConfig, views []chirpView) error {
+	userID := principalFromContext(r.Context()).UserID
+	if userID == uuid.Nil || len(views) == 0 {
+		return nil
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(views))
+	for _, view := range views {
+		chirpIDs = append(chirpIDs, view.ID)
+	}
+	liked, err := cfg.db.GetLikedChirpIDs(r.Context(), database.GetLikedChirpIDsParams{
+		UserID:   userID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return err
+	}
+	likedSet := make(map[uuid.UUID]bool, len(liked))
+	for _, id := range liked {
+		likedSet[id] = true
+	}
+	for i := range views {
+		likedByMe := likedSet[views[i].ID]
+		views[i].LikedByMe = &likedByMe
+	}
+	return nil
+}
+
+func likeChirp(cfg *apiConfig) http.HandlerFunc {
+	return setChirpLike(cfg, true)
+}
+
+func unlikeChirp(cfg *apiConfig) http.HandlerFunc {
+	return setChirpLike(cfg, false)
+}
+
+// setChirpLike likes or unlikes a chirp for the caller and answers with the
+// chirp. Repeating either request changes nothing.
+func setChirpLike(cfg *apiConfig, like bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := principalFromContext(r.Context()).UserID
+		chirpIDString := r.PathValue("chirpID")
+		chirpID, err := uuid.Parse(chirpIDString)
+		if err != nil {
+			errorMessage := "Invalid chirp ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		chirp, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		blocked, err := blockedEitherWay(r.Context(), cfg, userID, chirp.UserID)
+		if err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if blocked {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		if like {
+			_, err = cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+				ChirpID: chirpID,
+				UserID:  userID,
+			})
+		} else {
+			_, err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+				ChirpID: chirpID,
+				UserID:  userID,
+			})
+		}
+		if err != nil {
+			errorMessage := "Cannot update like"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		// Read the chirp again for the like count the trigger left behind.
+		chirp, err = cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		views := []chirpView{newChirpView(chirp)}
+		if err := embedQuotedChirps(r.Context(), cfg, views); err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		vis, err := viewerVisibility(r, cfg)
+		if err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		vis.maskQuotes(views)
+		views[0].LikedByMe = &like
+		data, err := json.Marshal(views[0])
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// getChirpLikes lists the users who liked a chirp, most recent like first.
+// Users the caller blocked or muted are left out.
+func getChirpLikes(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chirpIDString := r.PathValue("chirpID")
+		chirpID, err := uuid.Parse(chirpIDString)
+		if err != nil {
+			errorMessage := "Invalid chirp ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			responseError(w, err.Error(), 400)
+			return
+		}
+		chirp, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		vis, err := viewerVisibility(r, cfg)
+		if err != nil {
+			errorMessage := "Cannot retrieve likes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if vis.blocked[chirp.UserID] {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		users, err := cfg.db.GetChirpLikers(r.Context(), database.GetChirpLikersParams{
+			ChirpID: chirpID,
+			Limit:   limit,
+			Offset:  offset,
+		})
+		if err != nil {
+			errorMessage := "Cannot retrieve likes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		views := []userProfileView{}
+		for _, user := range users {
+			if vis.hides(user.ID) {
+				continue
+			}
+			views = append(views, newUserProfileView(user))
+		}
+		data, err := json.Marshal(views)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// getUserLikes lists the chirps a user liked, most recent like first.
+func getUserLikes(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			errorMessage := "Invalid user ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			responseError(w, err.Error(), 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil || user.DeletionScheduledAt.Valid {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		chirps, err := cfg.db.GetUserLikedChirps(r.Context(), database.GetUserLikedChirpsParams{
+			UserID: user.ID,
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			errorMessage := "Cannot retrieve likes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		views, err := prepareChirpViews(r, cfg, newChirpViews(chirps))
+		if err != nil {
+			errorMessage := "Cannot retrieve likes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		data, err := json.Marshal(views)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/hrncacz/go-chirpy/internal/auth"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+const recoveryCodeCount = 10
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code of the user. Both are single use.
+func checkSecondFactor(ctx context.Context, cfg *apiConfig, user database.User, code, recoveryCode string) (bool, error) {
+	if len(recoveryCode) > 0 {
+		used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode), cfg.tokenHashSecret),
+			UserID:   user.ID,
+		})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+	step, err := auth.ValidateTOTP(user.TotpSecret.String, code, time.Now(), user.TotpLastStep)
+	if errors.Is(err, auth.ErrTOTPInvalid) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	updated, err := cfg.db.UpdateUserTOTPLastStep(ctx, database.UpdateUserTOTPLastStepParams{
+		ID:           user.ID,
+		TotpLastStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated == 1, nil
+}
+
+func loginMFA(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reqBody struct {
+			MFATicket    string `json:"mfa_ticket"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		decoder := json.NewDecoder(r.Body)
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		ticketHash := auth.HashToken(req.MFATicket, cfg.tokenHashSecret)
+		ticket, err := cfg.db.GetMFATicket(r.Context(), ticketHash)
+		if err != nil {
+			errorMessage := "Invalid or expired MFA ticket"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), ticket.UserID)
+		if err != nil || !user.TotpEnabledAt.Valid {
+			errorMessage := "Unauthorized"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		accountKey := loginAccountKey(user.Email)
+		if !allowLoginAttempt(w, r, cfg, accountKey) {
+			return
+		}
+		ok, err := checkSecondFactor(r.Context(), cfg, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			errorMessage := "Cannot verify code"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if !ok {
+			failLoginAttempt(r, cfg, accountKey)
+			if err := cfg.db.RecordMFATicketFailure(r.Context(), ticketHash); err != nil {
+				errorMessage := "Cannot verify code"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			errorMessage := "Invalid code"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		consumed, err := cfg.db.ConsumeMFATicket(r.Context(), ticketHash)
+		if err != nil || consumed == 0 {
+			errorMessage := "Invalid or expired MFA ticket"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		succeedLoginAttempt(r, cfg, accountKey)
+		responseLogin(w, r, cfg, user)
+	}
+}
+
+func enrollTOTP(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type resBody struct {
+			Secret     string `json:"secret"`
+			OTPAuthURI string `json:"otpauth_uri"`
+		}
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		if user.TotpEnabledAt.Valid {
+			errorMessage := "Two-factor authentication is already enabled"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		secret, err := auth.GenerateTOTPSecret()
+		if err != nil {
+			errorMessage := "Cannot create TOTP secret"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		err = cfg.db.SetUserTOTPSecret(r.Context(), database.SetUserTOTPSecretParams{
+			ID:         user.ID,
+			TotpSecret: sql.NullString{String: secret, Valid: true},
+		})
+		if err != nil {
+			errorMessage := "Cannot create TOTP secret"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := resBody{
+			Secret:     secret,
+			OTPAuthURI: auth.TOTPURI("Chirpy", user.Email, secret),
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+func confirmTOTP(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reqBody struct {
+			Code string `json:"code"`
+		}
+		type resBody struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		if user.TotpEnabledAt.Valid {
+			errorMessage := "Two-factor authentication is already enabled"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		if !user.TotpSecret.Valid {
+			errorMessage := "Two-factor enrollment was not started"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		step, err := auth.ValidateTOTP(user.TotpSecret.String, req.Code, time.Now(), user.TotpLastStep)
+		if err != nil {
+			errorMessage := "Invalid code"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		recoveryCodes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+		if err != nil {
+			errorMessage := "Cannot create recovery codes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if err := cfg.db.DeleteUserRecoveryCodes(r.Context(), user.ID); err != nil {
+			errorMessage := "Cannot create recovery codes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		for _, recoveryCode := range recoveryCodes {
+			err := cfg.db.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+				CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode), cfg.tokenHashSecret),
+				UserID:   user.ID,
+			})
+			if err != nil {
+				errorMessage := "Cannot create recovery codes"
+				responseError(w, errorMessage, 500)
+				return
+			}
+		}
+		err = cfg.db.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+			ID:           user.ID,
+			TotpLastStep: step,
+		})
+		if err != nil {
+			errorMessage := "Cannot enable two-factor authentication"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := resBody{
+			RecoveryCodes: recoveryCodes,
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+func disableTOTP(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reqBody struct {
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		if !user.TotpEnabledAt.Valid {
+			errorMessage := "Two-factor authentication is not enabled"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		ok, err := checkSecondFactor(r.Context(), cfg, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			errorMessage := "Cannot verify code"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if !ok {
+			errorMessage := "Invalid code"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		if err := cfg.db.DisableUserTOTP(r.Context(), user.ID); err != nil {
+			errorMessage := "Cannot disable two-factor authentication"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if err := cfg.db.DeleteUserRecoveryCodes(r.Context(), user.ID); err != nil {
+			errorMessage := "Cannot disable two-factor authentication"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.WriteHeader(204)
+	}
+}
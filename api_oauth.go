@@ -0,0 +1,579 @@
+package main
+
+import (
+	"crypto/hmac"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/auth"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+const oauthAuthorizationCodeExpiration = 5 * time.Minute
+
+var oauthScopeDescriptions = map[string]string{
+	auth.ScopeOpenID:       "Confirm your identity",
+	auth.ScopeEmail:        "See your email address",
+	auth.ScopeChirpsRead:   "Read chirps",
+	auth.ScopeChirpsWrite:  "Post and delete chirps as you",
+	auth.ScopeProfileWrite: "Change your email address and password",
+}
+
+var authorizeTemplate = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Sign in to Chirpy</title>
+  </head>
+  <body>
+    {{if .Client}}
+    <h1>{{.Client.Name}} wants to access your Chirpy account</h1>
+    <ul>
+      {{range .Scopes}}<li>{{.}}</li>{{end}}
+    </ul>
+    {{end}}
+    {{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+    {{if .Client}}
+    <form method="post" action="/oauth/authorize">
+      {{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">{{end}}
+      <label>Email <input type="email" name="email" value="{{.Email}}" required></label>
+      <label>Password <input type="password" name="password" required></label>
+      {{if .NeedsCode}}<label>Authentication code <input type="text" name="code" inputmode="numeric" autocomplete="one-time-code"></label>{{end}}
+      <button type="submit" name="action" value="approve">Allow</button>
+      <button type="submit" name="action" value="deny" formnovalidate>Deny</button>
+    </form>
+    {{end}}
+  </body>
+</html>
+`))
+
+type authorizePage struct {
+	Client    *database.OauthClient
+	Scopes    []string
+	Params    map[string]string
+	Email     string
+	NeedsCode bool
+	Error     string
+}
+
+func renderAuthorizePage(w http.ResponseWriter, page authorizePage, code int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+	w.WriteHeader(code)
+	if err := authorizeTemplate.Execute(w, page); err != nil {
+		log.Printf("Error rendering authorize page: %s\n", err)
+	}
+}
+
+// authorizationRequest is a validated request to /oauth/authorize.
+type authorizationRequest struct {
+	Client        database.OauthClient
+	RedirectURI   string
+	State         string
+	Scopes        []string
+	CodeChallenge string
+	Nonce         string
+}
+
+func (a authorizationRequest) page() authorizePage {
+	scopes := []string{}
+	for _, scope := range a.Scopes {
+		scopes = append(scopes, oauthScopeDescriptions[scope])
+	}
+	return authorizePage{
+		Client: &a.Client,
+		Scopes: scopes,
+		Params: map[string]string{
+			"response_type":         "code",
+			"client_id":             a.Client.ID,
+			"redirect_uri":          a.RedirectURI,
+			"state":                 a.State,
+			"scope":                 strings.Join(a.Scopes, " "),
+			"code_challenge":        a.CodeChallenge,
+			"code_challenge_method": "S256",
+			"nonce":                 a.Nonce,
+		},
+	}
+}
+
+// redirect sends the browser back to the client with the given parameters.
+func (a authorizationRequest) redirect(w http.ResponseWriter, r *http.Request, cfg *apiConfig, params url.Values) {
+	redirectURI, _ := url.Parse(a.RedirectURI)
+	query := redirectURI.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if len(a.State) > 0 {
+		query.Set("state", a.State)
+	}
+	// RFC 9207 lets the client check which server answered.
+	query.Set("iss", cfg.baseURL)
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (a authorizationRequest) redirectError(w http.ResponseWriter, r *http.Request, cfg *apiConfig, errorCode, description string) {
+	a.redirect(w, r, cfg, url.Values{
+		"error":             {errorCode},
+		"error_description": {description},
+	})
+}
+
+// parseAuthorizationRequest validates the client and redirect URI first. If
+// either is wrong the user must not be redirected, so ok is false and an
+// error page has been rendered. Other problems are returned as an OAuth
+// error code to send back to the client.
+func parseAuthorizationRequest(w http.ResponseWriter, r *http.Request, cfg *apiConfig) (req authorizationRequest, errorCode, description string, ok bool) {
+	client, err := cfg.db.GetOAuthClient(r.Context(), r.FormValue("client_id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		renderAuthorizePage(w, authorizePage{Error: "Unknown client"}, 400)
+		return req, "", "", false
+	}
+	if err != nil {
+		renderAuthorizePage(w, authorizePage{Error: "Cannot load client"}, 500)
+		return req, "", "", false
+	}
+	req.Client = client
+	req.RedirectURI = r.FormValue("redirect_uri")
+	if len(req.RedirectURI) == 0 && len(client.RedirectUris) == 1 {
+		req.RedirectURI = client.RedirectUris[0]
+	}
+	if !slices.Contains(client.RedirectUris, req.RedirectURI) {
+		renderAuthorizePage(w, authorizePage{Error: "Redirect URI is not registered for this client"}, 400)
+		return req, "", "", false
+	}
+	req.State = r.FormValue("state")
+	req.Nonce = r.FormValue("nonce")
+	req.CodeChallenge = r.FormValue("code_challenge")
+	if r.FormValue("response_type") != "code" {
+		return req, "unsupported_response_type", "Only the code response type is supported", true
+	}
+	if !slices.Contains(client.GrantTypes, grantTypeAuthorizationCode) {
+		return req, "unauthorized_client", "Client may not use the authorization code grant", true
+	}
+	req.Scopes = strings.Fields(r.FormValue("scope"))
+	if len(req.Scopes) == 0 {
+		req.Scopes = client.Scopes
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return req, "invalid_scope", "Scope is not allowed for this client: " + scope, true
+		}
+	}
+	if len(req.CodeChallenge) == 0 || r.FormValue("code_challenge_method") != "S256" {
+		return req, "invalid_request", "PKCE with the S256 method is required", true
+	}
+	return req, "", "", true
+}
+
+func authorize(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, errorCode, description, ok := parseAuthorizationRequest(w, r, cfg)
+		if !ok {
+			return
+		}
+		if len(errorCode) > 0 {
+			req.redirectError(w, r, cfg, errorCode, description)
+			return
+		}
+		renderAuthorizePage(w, req.page(), 200)
+	}
+}
+
+// approveAuthorization signs the user in with the form on the authorize page
+// and sends an authorization code to the client.
+func approveAuthorization(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, errorCode, description, ok := parseAuthorizationRequest(w, r, cfg)
+		if !ok {
+			return
+		}
+		if len(errorCode) > 0 {
+			req.redirectError(w, r, cfg, errorCode, description)
+			return
+		}
+		if r.FormValue("action") == "deny" {
+			req.redirectError(w, r, cfg, "access_denied", "The user denied access")
+			return
+		}
+		email := r.FormValue("email")
+		page := req.page()
+		page.Email = email
+		accountKey := loginAccountKey(email)
+		accountRetryAfter, accountErr := cfg.loginAccountLimiter.Check(r.Context(), accountKey)
+		ipRetryAfter, ipErr := cfg.loginIPLimiter.Check(r.Context(), loginIPKey(r))
+		if accountErr != nil || ipErr != nil {
+			page.Error = "Cannot check login attempts"
+			renderAuthorizePage(w, page, 500)
+			return
+		}
+		if max(accountRetryAfter, ipRetryAfter) > 0 {
+			page.Error = "Too many failed attempts, try again later"
+			renderAuthorizePage(w, page, 429)
+			return
+		}
+		user, err := cfg.db.GetUserByEmail(r.Context(), email)
+		if err != nil {
+			failLoginAttempt(r, cfg, accountKey)
+			page.Error = "Wrong email or password"
+			renderAuthorizePage(w, page, 401)
+			return
+		}
+		needsRehash, err := cfg.passwords.Verify(r.FormValue("password"), user.HashedPassword)
+		if err != nil {
+			failLoginAttempt(r, cfg, accountKey)
+			page.Error = "Wrong email or password"
+			renderAuthorizePage(w, page, 401)
+			return
+		}
+		if needsRehash {
+			rehashPassword(r, cfg, user, r.FormValue("password"))
+		}
+		if user.TotpEnabledAt.Valid {
+			page.NeedsCode = true
+			code := r.FormValue("code")
+			if len(code) == 0 {
+				page.Error = "Enter the code from your authenticator app"
+				renderAuthorizePage(w, page, 401)
+				return
+			}
+			valid, err := checkSecondFactor(r.Context(), cfg, user, code, "")
+			if err != nil {
+				page.Error = "Cannot verify code"
+				renderAuthorizePage(w, page, 500)
+				return
+			}
+			if !valid {
+				failLoginAttempt(r, cfg, accountKey)
+				page.Error = "Invalid authentication code"
+				renderAuthorizePage(w, page, 401)
+				return
+			}
+		}
+		succeedLoginAttempt(r, cfg, accountKey)
+		code, err := auth.MakeToken()
+		if err != nil {
+			req.redirectError(w, r, cfg, "server_error", "Cannot issue authorization code")
+			return
+		}
+		err = cfg.db.CreateOAuthAuthorizationCode(r.Context(), database.CreateOAuthAuthorizationCodeParams{
+			CodeHash:      auth.HashToken(code, cfg.tokenHashSecret),
+			ClientID:      req.Client.ID,
+			UserID:        user.ID,
+			RedirectUri:   req.RedirectURI,
+			Scopes:        req.Scopes,
+			CodeChallenge: req.CodeChallenge,
+			Nonce:         req.Nonce,
+			AuthTime:      time.Now(),
+			ExpiresAt:     time.Now().Add(oauthAuthorizationCodeExpiration),
+		})
+		if err != nil {
+			log.Printf("Error creating authorization code: %s\n", err)
+			req.redirectError(w, r, cfg, "server_error", "Cannot issue authorization code")
+			return
+		}
+		req.redirect(w, r, cfg, url.Values{"code": {code}})
+	}
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// responseOAuthError answers a token request with an RFC 6749 error.
+func responseOAuthError(w http.ResponseWriter, code int, errorCode, description string) {
+	type resError struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}
+	data, err := json.Marshal(resError{
+		Error:            errorCode,
+		ErrorDescription: description,
+	})
+	if err != nil {
+		log.Printf("Error marshalling error: %s\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// authenticateOAuthClient reads client credentials from HTTP basic auth or
+// the form body. Public clients only send their client_id.
+func authenticateOAuthClient(r *http.Request, cfg *apiConfig) (database.OauthClient, bool) {
+	clientID, clientSecret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 form-encodes both values.
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostFormValue("client_id")
+		clientSecret = r.PostFormValue("client_secret")
+	}
+	client, err := cfg.db.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		return client, false
+	}
+	if !client.SecretHash.Valid {
+		return client, len(clientSecret) == 0
+	}
+	secretHash := auth.HashToken(clientSecret, cfg.tokenHashSecret)
+	return client, hmac.Equal([]byte(secretHash), []byte(client.SecretHash.String))
+}
+
+func oauthToken(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			responseOAuthError(w, 400, "invalid_request", "Cannot parse form")
+			return
+		}
+		client, ok := authenticateOAuthClient(r, cfg)
+		if !ok {
+			if _, _, basic := r.BasicAuth(); basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+			}
+			responseOAuthError(w, 401, "invalid_client", "Client authentication failed")
+			return
+		}
+		grantType := r.PostFormValue("grant_type")
+		if !slices.Contains(oauthGrantTypes, grantType) {
+			responseOAuthError(w, 400, "unsupported_grant_type", "Unsupported grant type")
+			return
+		}
+		if !slices.Contains(client.GrantTypes, grantType) {
+			responseOAuthError(w, 400, "unauthorized_client", "Client may not use this grant type")
+			return
+		}
+		switch grantType {
+		case grantTypeAuthorizationCode:
+			exchangeAuthorizationCode(w, r, cfg, client)
+		case grantTypeRefreshToken:
+			exchangeRefreshToken(w, r, cfg, client)
+		case grantTypeClientCredentials:
+			issueClientCredentialsToken(w, r, cfg, client)
+		}
+	}
+}
+
+func exchangeAuthorizationCode(w http.ResponseWriter, r *http.Request, cfg *apiConfig, client database.OauthClient) {
+	codeHash := auth.HashToken(r.PostFormValue("code"), cfg.tokenHashSecret)
+	code, err := cfg.db.ConsumeOAuthAuthorizationCode(r.Context(), codeHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		// A code used twice may have been intercepted, so the tokens issued
+		// for it are revoked as RFC 6749 section 4.1.2 recommends.
+		storedCode, err := cfg.db.GetOAuthAuthorizationCode(r.Context(), codeHash)
+		if err == nil && storedCode.UsedAt.Valid && storedCode.FamilyID.Valid {
+			if err := cfg.db.RevokeRefreshTokenFamily(r.Context(), storedCode.FamilyID.UUID); err != nil {
+				log.Printf("Error revoking refresh token family %s: %s\n", storedCode.FamilyID.UUID, err)
+			}
+		}
+		responseOAuthError(w, 400, "invalid_grant", "Invalid or expired authorization code")
+		return
+	}
+	if err != nil {
+		responseOAuthError(w, 500, "server_error", "Cannot check authorization code")
+		return
+	}
+	if code.ClientID != client.ID || code.RedirectUri != r.PostFormValue("redirect_uri") {
+		responseOAuthError(w, 400, "invalid_grant", "Authorization code was issued to another client or redirect URI")
+		return
+	}
+	if !auth.VerifyPKCE(r.PostFormValue("code_verifier"), code.CodeChallenge) {
+		responseOAuthError(w, 400, "invalid_grant", "Invalid code verifier")
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), code.UserID)
+	if err != nil {
+		responseOAuthError(w, 400, "invalid_grant", "User no longer exists")
+		return
+	}
+	res, familyID, err := issueOAuthTokens(r, cfg, client, user, code.Scopes, code.Nonce, code.AuthTime, nil)
+	if err != nil {
+		log.Printf("Error issuing OAuth tokens: %s\n", err)
+		responseOAuthError(w, 500, "server_error", "Cannot issue tokens")
+		return
+	}
+	if familyID.Valid {
+		err = cfg.db.SetOAuthAuthorizationCodeFamily(r.Context(), database.SetOAuthAuthorizationCodeFamilyParams{
+			CodeHash: codeHash,
+			FamilyID: familyID,
+		})
+		if err != nil {
+			log.Printf("Error linking authorization code to tokens: %s\n", err)
+		}
+	}
+	responseOAuthToken(w, res)
+}
+
+func exchangeRefreshToken(w http.ResponseWriter, r *http.Request, cfg *apiConfig, client database.OauthClient) {
+	refreshTokenHash := auth.HashToken(r.PostFormValue("refresh_token"), cfg.tokenHashSecret)
+	oldToken, err := cfg.db.ConsumeClientRefreshToken(r.Context(), database.ConsumeClientRefreshTokenParams{
+		TokenHash: refreshTokenHash,
+		ClientID:  sql.NullString{String: client.ID, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		storedToken, err := cfg.db.GetRefreshToken(r.Context(), refreshTokenHash)
+		if err == nil && storedToken.RevokedAt.Valid && storedToken.ClientID.String == client.ID {
+			if err := cfg.db.RevokeRefreshTokenFamily(r.Context(), storedToken.FamilyID); err != nil {
+				log.Printf("Error revoking refresh token family %s: %s\n", storedToken.FamilyID, err)
+			}
+		}
+		responseOAuthError(w, 400, "invalid_grant", "Invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		responseOAuthError(w, 500, "server_error", "Cannot check refresh token")
+		return
+	}
+	// The client may ask for fewer scopes than were originally granted.
+	scopes := oldToken.Scopes
+	if requested := strings.Fields(r.PostFormValue("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(oldToken.Scopes, scope) {
+				responseOAuthError(w, 400, "invalid_scope", "Scope was not granted: "+scope)
+				return
+			}
+		}
+		scopes = requested
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), oldToken.UserID)
+	if err != nil {
+		responseOAuthError(w, 400, "invalid_grant", "User no longer exists")
+		return
+	}
+	res, _, err := issueOAuthTokens(r, cfg, client, user, scopes, "", oldToken.SessionStartedAt, &oldToken)
+	if err != nil {
+		log.Printf("Error issuing OAuth tokens: %s\n", err)
+		responseOAuthError(w, 500, "server_error", "Cannot issue tokens")
+		return
+	}
+	responseOAuthToken(w, res)
+}
+
+// issueClientCredentialsToken gives a confidential client an access token for
+// itself. Its subject is the client ID rather than a user.
+func issueClientCredentialsToken(w http.ResponseWriter, r *http.Request, cfg *apiConfig, client database.OauthClient) {
+	if !client.SecretHash.Valid {
+		responseOAuthError(w, 400, "unauthorized_client", "Public clients cannot use client credentials")
+		return
+	}
+	allowed := []string{}
+	for _, scope := range client.Scopes {
+		if scope != auth.ScopeOpenID && scope != auth.ScopeEmail {
+			allowed = append(allowed, scope)
+		}
+	}
+	scopes := allowed
+	if requested := strings.Fields(r.PostFormValue("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(allowed, scope) {
+				responseOAuthError(w, 400, "invalid_scope", "Scope is not allowed for this client: "+scope)
+				return
+			}
+		}
+		scopes = requested
+	}
+	claims := auth.NewClaims(client.ID, cfg.jwtExpiration)
+	claims.Scope = strings.Join(scopes, " ")
+	claims.ClientID = client.ID
+	accessToken, err := cfg.jwtKeys.Sign(claims)
+	if err != nil {
+		responseOAuthError(w, 500, "server_error", "Cannot issue tokens")
+		return
+	}
+	responseOAuthToken(w, oauthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(cfg.jwtExpiration.Seconds()),
+		Scope:       claims.Scope,
+	})
+}
+
+// issueOAuthTokens creates the access token, a refresh token if the client
+// may refresh, and an ID token if openid was granted. It returns the family
+// of the new refresh token.
+func issueOAuthTokens(r *http.Request, cfg *apiConfig, client database.OauthClient, user database.User, scopes []string, nonce string, authTime time.Time, parent *database.RefreshToken) (oauthTokenResponse, uuid.NullUUID, error) {
+	res := oauthTokenResponse{
+		TokenType: "Bearer",
+		ExpiresIn: int(cfg.jwtExpiration.Seconds()),
+		Scope:     strings.Join(scopes, " "),
+	}
+	claims := auth.NewClaims(user.ID.String(), cfg.jwtExpiration)
+	claims.Role = user.Role
+	claims.Scope = res.Scope
+	claims.ClientID = client.ID
+	accessToken, err := cfg.jwtKeys.Sign(claims)
+	if err != nil {
+		return res, uuid.NullUUID{}, err
+	}
+	res.AccessToken = accessToken
+	familyID := uuid.NullUUID{}
+	if slices.Contains(client.GrantTypes, grantTypeRefreshToken) {
+		grant := refreshTokenGrant{
+			ClientID: sql.NullString{String: client.ID, Valid: true},
+			Scopes:   scopes,
+		}
+		res.RefreshToken, familyID.UUID, err = createRefreshToken(r, cfg, user.ID, grant, parent)
+		if err != nil {
+			return res, uuid.NullUUID{}, err
+		}
+		familyID.Valid = true
+	}
+	if slices.Contains(scopes, auth.ScopeOpenID) {
+		currentTime := time.Now()
+		idClaims := auth.IDTokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    cfg.baseURL,
+				Subject:   user.ID.String(),
+				Audience:  jwt.ClaimStrings{client.ID},
+				IssuedAt:  jwt.NewNumericDate(currentTime),
+				ExpiresAt: jwt.NewNumericDate(currentTime.Add(cfg.jwtExpiration)),
+			},
+			Nonce:    nonce,
+			AuthTime: jwt.NewNumericDate(authTime),
+		}
+		if slices.Contains(scopes, auth.ScopeEmail) {
+			emailVerified := user.EmailVerifiedAt.Valid
+			idClaims.Email = user.Email
+			idClaims.EmailVerified = &emailVerified
+		}
+		res.IDToken, err = cfg.jwtKeys.Sign(idClaims)
+		if err != nil {
+			return res, uuid.NullUUID{}, err
+		}
+	}
+	return res, familyID, nil
+}
+
+func responseOAuthToken(w http.ResponseWriter, res oauthTokenResponse) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		responseOAuthError(w, 500, "server_error", "Cannot marshal response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(200)
+	w.Write(data)
+}
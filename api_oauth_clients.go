@@ -0,0 +1,235 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/auth"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+	grantTypeClientCredentials = "client_credentials"
+)
+
+var oauthGrantTypes = []string{grantTypeAuthorizationCode, grantTypeRefreshToken, grantTypeClientCredentials}
+
+// oauthScopes lists every scope an OAuth client can be registered for.
+func oauthScopes() []string {
+	return append([]string{auth.ScopeOpenID, auth.ScopeEmail}, auth.Scopes...)
+}
+
+type oauthClientView struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	Confidential bool      `json:"confidential"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newOAuthClientView(client database.OauthClient) oauthClientView {
+	return oauthClientView{
+		ClientID:     client.ID,
+		Name:         client.Name,
+		Confidential: client.SecretHash.Valid,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		GrantTypes:   client.GrantTypes,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// validRedirectURI accepts absolute URIs without a fragment. Plain http is
+// only allowed for loopback addresses used by native apps.
+func validRedirectURI(redirectURI string) bool {
+	u, err := url.Parse(redirectURI)
+	if err != nil || !u.IsAbs() || len(u.Fragment) > 0 {
+		return false
+	}
+	if u.Scheme == "http" {
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return true
+}
+
+func createOAuthClient(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reqBody struct {
+			Name string `json:"name"`
+			// Confidential clients get a secret. Public clients rely on
+			// PKCE alone.
+			Confidential bool     `json:"confidential"`
+			RedirectURIs []string `json:"redirect_uris"`
+			Scopes       []string `json:"scopes"`
+			GrantTypes   []string `json:"grant_types"`
+		}
+		type resBody struct {
+			oauthClientView
+			ClientSecret string `json:"client_secret,omitempty"`
+		}
+		if !principalFromContext(r.Context()).hasRole(auth.RoleAdmin) {
+			errorMessage := "Only admins can register OAuth clients"
+			responseError(w, errorMessage, 403)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if len(req.Name) == 0 || len(req.Name) > 100 {
+			errorMessage := "Name must be between 1 and 100 characters long"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if len(req.GrantTypes) == 0 {
+			req.GrantTypes = []string{grantTypeAuthorizationCode, grantTypeRefreshToken}
+		}
+		for _, grantType := range req.GrantTypes {
+			if !slices.Contains(oauthGrantTypes, grantType) {
+				errorMessage := fmt.Sprintf("Unknown grant type: %s", grantType)
+				responseError(w, errorMessage, 400)
+				return
+			}
+		}
+		if slices.Contains(req.GrantTypes, grantTypeClientCredentials) && !req.Confidential {
+			errorMessage := "The client_credentials grant needs a confidential client"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if slices.Contains(req.GrantTypes, grantTypeAuthorizationCode) && len(req.RedirectURIs) == 0 {
+			errorMessage := "At least one redirect URI is required"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		for _, redirectURI := range req.RedirectURIs {
+			if !validRedirectURI(redirectURI) {
+				errorMessage := fmt.Sprintf("Invalid redirect URI: %s", redirectURI)
+				responseError(w, errorMessage, 400)
+				return
+			}
+		}
+		if len(req.Scopes) == 0 {
+			errorMessage := "At least one scope is required"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		for _, scope := range req.Scopes {
+			if !slices.Contains(oauthScopes(), scope) {
+				errorMessage := fmt.Sprintf("Unknown scope: %s", scope)
+				responseError(w, errorMessage, 400)
+				return
+			}
+		}
+		params := database.CreateOAuthClientParams{
+			ID:           uuid.New().String(),
+			Name:         req.Name,
+			RedirectUris: req.RedirectURIs,
+			Scopes:       req.Scopes,
+			GrantTypes:   req.GrantTypes,
+		}
+		if params.RedirectUris == nil {
+			params.RedirectUris = []string{}
+		}
+		clientSecret := ""
+		if req.Confidential {
+			var err error
+			clientSecret, err = auth.MakeToken()
+			if err != nil {
+				errorMessage := "Cannot create client"
+				responseError(w, errorMessage, 500)
+				return
+			}
+			params.SecretHash = sql.NullString{
+				String: auth.HashToken(clientSecret, cfg.tokenHashSecret),
+				Valid:  true,
+			}
+		}
+		client, err := cfg.db.CreateOAuthClient(r.Context(), params)
+		if err != nil {
+			log.Printf("Error creating OAuth client: %s\n", err)
+			errorMessage := "Cannot create client"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := resBody{
+			oauthClientView: newOAuthClientView(client),
+			ClientSecret:    clientSecret,
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(201)
+		w.Write(data)
+	}
+}
+
+func getOAuthClients(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !principalFromContext(r.Context()).hasRole(auth.RoleAdmin) {
+			errorMessage := "Only admins can list OAuth clients"
+			responseError(w, errorMessage, 403)
+			return
+		}
+		clients, err := cfg.db.GetOAuthClients(r.Context())
+		if err != nil {
+			errorMessage := "Cannot retrieve clients"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := []oauthClientView{}
+		for _, client := range clients {
+			res = append(res, newOAuthClientView(client))
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+func deleteOAuthClient(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !principalFromContext(r.Context()).hasRole(auth.RoleAdmin) {
+			errorMessage := "Only admins can delete OAuth clients"
+			responseError(w, errorMessage, 403)
+			return
+		}
+		// Codes and refresh tokens of the client are removed with it.
+		deleted, err := cfg.db.DeleteOAuthClient(r.Context(), r.PathValue("clientID"))
+		if err != nil {
+			errorMessage := "Cannot delete client"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if deleted == 0 {
+			errorMessage := "Client was not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		w.WriteHeader(204)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hrncacz/go-chirpy/internal/auth"
+	"github.com/hrncacz/go-chirpy/internal/database"
+	"github.com/hrncacz/go-chirpy/internal/oidc"
+)
+
+const (
+	oidcStateCookie     = "chirpy_oidc_state"
+	oidcStateExpiration = 10 * time.Minute
+)
+
+var (
+	errExternalLoginNoEmail    = errors.New("identity provider did not share an email address")
+	errExternalLoginEmailTaken = errors.New("email address belongs to another account")
+)
+
+// startOIDCLogin sends the browser to the identity provider. The state is
+// also set as a cookie so the callback only completes in the browser that
+// started the login.
+func startOIDCLogin(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider, ok := cfg.oidcProviders[r.PathValue("provider")]
+		if !ok {
+			errorMessage := "Unknown identity provider"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		state, stateErr := auth.MakeToken()
+		nonce, nonceErr := auth.MakeToken()
+		codeVerifier, verifierErr := auth.MakeToken()
+		if stateErr != nil || nonceErr != nil || verifierErr != nil {
+			errorMessage := "Cannot start login"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		err := cfg.db.CreateOIDCLoginState(r.Context(), database.CreateOIDCLoginStateParams{
+			StateHash:    auth.HashToken(state, cfg.tokenHashSecret),
+			Provider:     provider.Name(),
+			Nonce:        nonce,
+			CodeVerifier: codeVerifier,
+			ExpiresAt:    time.Now().Add(oidcStateExpiration),
+		})
+		if err != nil {
+			log.Printf("Error storing OIDC login state: %s\n", err)
+			errorMessage := "Cannot start login"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    state,
+			Path:     "/api/login/oidc/",
+			MaxAge:   int(oidcStateExpiration.Seconds()),
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(cfg.baseURL, "https://"),
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, provider.AuthCodeURL(state, nonce, auth.PKCEChallenge(codeVerifier)), http.StatusFound)
+	}
+}
+
+// finishOIDCLogin handles the redirect back from the identity provider and
+// answers like POST /api/login.
+func finishOIDCLogin(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider, ok := cfg.oidcProviders[r.PathValue("provider")]
+		if !ok {
+			errorMessage := "Unknown identity provider"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		if providerError := r.URL.Query().Get("error"); len(providerError) > 0 {
+			errorMessage := "Identity provider refused the login: " + providerError
+			responseError(w, errorMessage, 401)
+			return
+		}
+		state := r.URL.Query().Get("state")
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil || len(state) == 0 || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+			errorMessage := "Login state does not match"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:   oidcStateCookie,
+			Path:   "/api/login/oidc/",
+			MaxAge: -1,
+		})
+		loginState, err := cfg.db.ConsumeOIDCLoginState(r.Context(), database.ConsumeOIDCLoginStateParams{
+			StateHash: auth.HashToken(state, cfg.tokenHashSecret),
+			Provider:  provider.Name(),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			errorMessage := "Login has expired, please start again"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if err != nil {
+			errorMessage := "Cannot finish login"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		rawIDToken, err := provider.Exchange(r.Context(), r.URL.Query().Get("code"), loginState.CodeVerifier)
+		if err != nil {
+			log.Printf("Error exchanging code with %s: %s\n", provider.Name(), err)
+			errorMessage := "Identity provider login failed"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		idToken, err := provider.VerifyIDToken(r.Context(), rawIDToken, loginState.Nonce)
+		if err != nil {
+			log.Printf("Error verifying ID token from %s: %s\n", provider.Name(), err)
+			errorMessage := "Identity provider login failed"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		user, err := resolveExternalUser(r.Context(), cfg, provider.Name(), idToken)
+		if errors.Is(err, errExternalLoginNoEmail) {
+			errorMessage := "Identity provider did not share an email address"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if errors.Is(err, errExternalLoginEmailTaken) {
+			errorMessage := "An account with this email address already exists, sign in with your password"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		if err != nil {
+			log.Printf("Error resolving external user: %s\n", err)
+			errorMessage := "Cannot finish login"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if user.TotpEnabledAt.Valid {
+			responseMFARequired(w, r, cfg, user)
+			return
+		}
+		responseLogin(w, r, cfg, user)
+	}
+}
+
+// resolveExternalUser finds the user linked to the external identity. An
+// unknown identity is linked to the local account with the same email only
+// when the provider has verified that address, otherwise a new account
+// without a password is created.
+func resolveExternalUser(ctx context.Context, cfg *apiConfig, provider string, idToken *oidc.IDToken) (database.User, error) {
+	identity, err := cfg.db.GetUserIdentity(ctx, database.GetUserIdentityParams{
+		Provider: provider,
+		Subject:  idToken.Subject,
+	})
+	if err == nil {
+		err = cfg.db.UpdateUserIdentityLogin(ctx, database.UpdateUserIdentityLoginParams{
+			ID:    identity.ID,
+			Email: idToken.Email,
+		})
+		if err != nil {
+			return database.User{}, err
+		}
+		return cfg.db.GetUserByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, err
+	}
+	if len(idToken.Email) == 0 {
+		return database.User{}, errExternalLoginNoEmail
+	}
+	user, err := cfg.db.GetUserByEmail(ctx, idToken.Email)
+	// Link only when both sides proved they own the address. Anyone can
+	// register an unverified local account for someone else's address and
+	// would keep access through its password once the identity is linked.
+	if err == nil && (!idToken.EmailVerified || !user.EmailVerifiedAt.Valid) {
+		return database.User{}, errExternalLoginEmailTaken
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		params := database.CreateExternalUserParams{
+			Email: idToken.Email,
+		}
+		if idToken.EmailVerified {
+			params.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+		user, err = cfg.db.CreateExternalUser(ctx, params)
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+	_, err = cfg.db.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		Provider: provider,
+		Subject:  idToken.Subject,
+		Email:    idToken.Email,
+		UserID:   user.ID,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	return user, nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+// rechirpView says who rechirped a chirp listed as a rechirp.
+type rechirpView struct {
+	UserID      uuid.UUID `json:"user_id"`
+	RechirpedAt time.Time `json:"rechirped_at"`
+}
+
+// quotedChirpView is the original embedded in a quote chirp. Once the
+// original is deleted only the tombstone {"deleted": true} is left.
+type quotedChirpView struct {
+	Deleted bool `json:"deleted"`
+	// Unavailable is set instead of the original when its author and the
+	// caller blocked or muted each other.
+	Unavailable bool `json:"unavailable,omitempty"`
+	*chirpView
+}
+
+// newRechirpView lists the chirp in row as rechirped by userID.
+func newRechirpView(row database.GetRechirpsByUserIDRow, userID uuid.UUID) chirpView {
+	view := newChirpView(database.Chirp{
+		ID:           row.ID,
+		CreatedAt:    row.CreatedAt,
+		UpdatedAt:    row.UpdatedAt,
+		Body:         row.Body,
+		UserID:       row.UserID,
+		EditedAt:     row.EditedAt,
+		EditCount:    row.EditCount,
+		InReplyToID:  row.InReplyToID,
+		RootID:       row.RootID,
+		ReplyCount:   row.ReplyCount,
+		LikeCount:    row.LikeCount,
+		QuoteOfID:    row.QuoteOfID,
+		RechirpCount: row.RechirpCount,
+		QuoteCount:   row.QuoteCount,
+	})
+	view.RechirpedBy = &rechirpView{
+		UserID:      userID,
+		RechirpedAt: row.RechirpedAt,
+	}
+	return view
+}
+
+// embedQuotedChirps fills in QuotedChirp for the quote chirps among views
+// with one query. The embedded originals do not embed their own quotes.
+func embedQuotedChirps(ctx context.Context, cfg *apiConfig, views []chirpView) error {
+	quotedIDs := []uuid.UUID{}
+	for _, view := range views {
+		if view.QuoteOfID != nil {
+			quotedIDs = append(quotedIDs, *view.QuoteOfID)
+		}
+	}
+	if len(quotedIDs) == 0 {
+		return nil
+	}
+	quoted, err := cfg.db.GetChirpsByIDs(ctx, quotedIDs)
+	if err != nil {
+		return err
+	}
+	originals := make(map[uuid.UUID]chirpView, len(quoted))
+	for _, chirp := range quoted {
+		originals[chirp.ID] = newChirpView(chirp)
+	}
+	for i := range views {
+		if views[i].QuoteOfID == nil {
+			continue
+		}
+		original, ok := originals[*views[i].QuoteOfID]
+		if !ok {
+			views[i].QuotedChirp = &quotedChirpView{Deleted: true}
+			continue
+		}
+		views[i].QuotedChirp = &quotedChirpView{chirpView: &original}
+	}
+	return nil
+}
+
+func rechirp(cfg *apiConfig) http.HandlerFunc {
+	return setRechirp(cfg, true)
+}
+
+func undoRechirp(cfg *apiConfig) http.HandlerFunc {
+	return setRechirp(cfg, false)
+}
+
+// setRechirp rechirps a chirp for the caller or takes the rechirp back and
+// answers with the chirp. Repeating either request changes nothing.
+func setRechirp(cfg *apiConfig, rechirped bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := principalFromContext(r.Context()).UserID
+		chirpIDString := r.PathValue("chirpID")
+		chirpID, err := uuid.Parse(chirpIDString)
+		if err != nil {
+			errorMessage := "Invalid chirp ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		chirp, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		blocked, err := blockedEitherWay(r.Context(), cfg, userID, chirp.UserID)
+		if err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if blocked {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		if rechirped {
+			_, err = cfg.db.Rechirp(r.Context(), database.RechirpParams{
+				ChirpID: chirpID,
+				UserID:  userID,
+			})
+		} else {
+			_, err = cfg.db.UndoRechirp(r.Context(), database.UndoRechirpParams{
+				ChirpID: chirpID,
+				UserID:  userID,
+			})
+		}
+		if err != nil {
+			errorMessage := "Cannot update rechirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		// Read the chirp again for the rechirp count the trigger left behind.
+		chirp, err = cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		views := []chirpView{newChirpView(chirp)}
+		if err := embedQuotedChirps(r.Context(), cfg, views); err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		vis, err := viewerVisibility(r, cfg)
+		if err != nil {
+			errorMessage := "Cannot retrieve chirp"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		vis.maskQuotes(views)
+		data, err := json.Marshal(views[0])
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// getChirpQuotes lists the quote chirps of a chirp, newest first.
+func getChirpQuotes(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chirpIDString := r.PathValue("chirpID")
+		chirpID, err := uuid.Parse(chirpIDString)
+		if err != nil {
+			errorMessage := "Invalid chirp ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			responseError(w, err.Error(), 400)
+			return
+		}
+		original, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		blocked, err := blockedEitherWay(r.Context(), cfg, principalFromContext(r.Context()).UserID, original.UserID)
+		if err != nil {
+			errorMessage := "Cannot retrieve quotes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if blocked {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		quotes, err := cfg.db.GetChirpQuotes(r.Context(), database.GetChirpQuotesParams{
+			QuoteOfID: uuid.NullUUID{UUID: original.ID, Valid: true},
+			Limit:     limit,
+			Offset:    offset,
+		})
+		if err != nil {
+			errorMessage := "Cannot retrieve quotes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		views, err := prepareChirpViews(r, cfg, newChirpViews(quotes))
+		if err != nil {
+			errorMessage := "Cannot retrieve quotes"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		data, err := json.Marshal(views)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/auth"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+func getSessions(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type session struct {
+			ID         uuid.UUID `json:"id"`
+			CreatedAt  time.Time `json:"created_at"`
+			LastUsedAt time.Time `json:"last_used_at"`
+			ExpiresAt  time.Time `json:"expires_at"`
+			UserAgent  string    `json:"user_agent"`
+			IPAddress  string    `json:"ip_address"`
+		}
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		rows, err := cfg.db.GetUserSessions(r.Context(), userID)
+		if err != nil {
+			errorMessage := "Cannot retrieve sessions"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := []session{}
+		for _, row := range rows {
+			res = append(res, session{
+				ID:         row.FamilyID,
+				CreatedAt:  row.SessionStartedAt,
+				LastUsedAt: row.LastUsedAt,
+				ExpiresAt:  row.ExpiresAt,
+				UserAgent:  row.UserAgent,
+				IPAddress:  row.IpAddress,
+			})
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+func deleteSession(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		sessionID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			errorMessage := "Invalid session ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		revoked, err := cfg.db.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+			FamilyID: sessionID,
+			UserID:   userID,
+		})
+		if err != nil {
+			errorMessage := "Cannot revoke session"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if revoked == 0 {
+			errorMessage := "Session was not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		w.WriteHeader(204)
+	}
+}
+
+func logoutAll(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		if err := cfg.db.RevokeAllUserRefreshTokens(r.Context(), userID); err != nil {
+			errorMessage := "Cannot revoke sessions"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.WriteHeader(204)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+// maxThreadReplies caps the reply tree returned with a thread.
+const maxThreadReplies = 500
+
+// getChirpReplies lists the direct replies to a chirp, oldest first.
+func getChirpReplies(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chirpIDString := r.PathValue("chirpID")
+		chirpID, err := uuid.Parse(chirpIDString)
+		if err != nil {
+			errorMessage := "Invalid chirp ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			responseError(w, err.Error(), 400)
+			return
+		}
+		parent, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		blocked, err := blockedEitherWay(r.Context(), cfg, principalFromContext(r.Context()).UserID, parent.UserID)
+		if err != nil {
+			errorMessage := "Cannot retrieve replies"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if blocked {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		replies, err := cfg.db.GetChirpReplies(r.Context(), database.GetChirpRepliesParams{
+			InReplyToID: uuid.NullUUID{UUID: chirpID, Valid: true},
+			Limit:       limit,
+			Offset:      offset,
+		})
+		if err != nil {
+			errorMessage := "Cannot retrieve replies"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		views, err := prepareChirpViews(r, cfg, newChirpViews(replies))
+		if err != nil {
+			errorMessage := "Cannot retrieve replies"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		data, err := json.Marshal(views)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// threadNode is a chirp with the replies below it.
+type threadNode struct {
+	chirpView
+	Replies []*threadNode `json:"replies"`
+}
+
+// getChirpThread returns the chain of chirps the chirp replies to, oldest
+// first, and the tree of replies below it.
+func getChirpThread(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type resBody struct {
+			Ancestors []chirpView `json:"ancestors"`
+			Chirp     *threadNode `json:"chirp"`
+			// Truncated is set when the reply tree was cut at
+			// maxThreadReplies.
+			Truncated bool `json:"truncated"`
+		}
+		chirpIDString := r.PathValue("chirpID")
+		chirpID, err := uuid.Parse(chirpIDString)
+		if err != nil {
+			errorMessage := "Invalid chirp ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		chirp, err := cfg.db.GetChirpsOne(r.Context(), chirpID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		vis, err := viewerVisibility(r, cfg)
+		if err != nil {
+			errorMessage := "Cannot retrieve thread"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if vis.blocked[chirp.UserID] {
+			errorMessage := fmt.Sprintf("Chirp was not found: %s", chirpIDString)
+			responseError(w, errorMessage, 404)
+			return
+		}
+		ancestors, err := cfg.db.GetChirpAncestors(r.Context(), chirp.ID)
+		if err != nil {
+			errorMessage := "Cannot retrieve thread"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		replies, err := cfg.db.GetChirpReplyTree(r.Context(), database.GetChirpReplyTreeParams{
+			InReplyToID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			Limit:       maxThreadReplies + 1,
+		})
+		if err != nil {
+			errorMessage := "Cannot retrieve thread"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		truncated := len(replies) > maxThreadReplies
+		if truncated {
+			replies = replies[:maxThreadReplies]
+		}
+		// The ancestors, the chirp and its replies get their quoted chirps
+		// and liked_by_me together.
+		views := append(newChirpViews(ancestors), newChirpView(chirp))
+		views = append(views, newChirpViews(replies)...)
+		if err := embedQuotedChirps(r.Context(), cfg, views); err != nil {
+			errorMessage := "Cannot retrieve thread"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		vis.maskQuotes(views)
+		if err := setLikedByMe(r, cfg, views); err != nil {
+			errorMessage := "Cannot retrieve thread"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		replyViews := views[len(ancestors)+1:]
+		res := resBody{
+			Ancestors: vis.filter(views[:len(ancestors)]),
+			Chirp:     &threadNode{chirpView: views[len(ancestors)], Replies: []*threadNode{}},
+			Truncated: truncated,
+		}
+		// Replies come oldest first, so a parent is always placed before
+		// its replies. Hiding a reply hides the replies below it too.
+		nodes := map[uuid.UUID]*threadNode{chirp.ID: res.Chirp}
+		for i, reply := range replies {
+			parent, ok := nodes[reply.InReplyToID.UUID]
+			if !ok || vis.hides(reply.UserID) {
+				continue
+			}
+			node := &threadNode{chirpView: replyViews[i], Replies: []*threadNode{}}
+			parent.Replies = append(parent.Replies, node)
+			nodes[reply.ID] = node
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeTimelineCursor returns an opaque cursor pointing just past the chirp.
+func encodeTimelineCursor(chirp database.Chirp) string {
+	raw := chirp.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + chirp.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTimelineCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	return t, chirpID, nil
+}
+
+// getTimeline returns the caller's home timeline, newest first: their own
+// chirps and those of the accounts they follow. Pass next_cursor from the
+// previous page as cursor to get the next one.
+func getTimeline(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type resBody struct {
+			Chirps     []chirpView `json:"chirps"`
+			NextCursor *string     `json:"next_cursor"`
+		}
+		userID := principalFromContext(r.Context()).UserID
+		limit, _, err := parsePagination(r)
+		if err != nil {
+			responseError(w, err.Error(), 400)
+			return
+		}
+		params := database.GetTimelineParams{
+			UserID: userID,
+			// One more than asked for tells whether there is a next page.
+			PageLimit: limit + 1,
+		}
+		if cursor := r.URL.Query().Get("cursor"); len(cursor) > 0 {
+			createdAt, chirpID, err := decodeTimelineCursor(cursor)
+			if err != nil {
+				errorMessage := "Invalid cursor"
+				responseError(w, errorMessage, 400)
+				return
+			}
+			params.BeforeCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+			params.BeforeID = uuid.NullUUID{UUID: chirpID, Valid: true}
+		}
+		chirps, err := cfg.db.GetTimeline(r.Context(), params)
+		if err != nil {
+			errorMessage := "Cannot retrieve timeline"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := resBody{}
+		if len(chirps) > int(limit) {
+			chirps = chirps[:limit]
+			nextCursor := encodeTimelineCursor(chirps[len(chirps)-1])
+			res.NextCursor = &nextCursor
+		}
+		res.Chirps, err = prepareChirpViews(r, cfg, newChirpViews(chirps))
+		if err != nil {
+			errorMessage := "Cannot retrieve timeline"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
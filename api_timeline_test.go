@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+func TestTimelineCursor(t *testing.T) {
+	chirp := database.Chirp{
+		ID:        uuid.New(),
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC),
+	}
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	cases := []struct {
+		cursor    string
+		createdAt time.Time
+		chirpID   uuid.UUID
+		expected  error
+	}{
+		{cursor: encodeTimelineCursor(chirp), createdAt: chirp.CreatedAt, chirpID: chirp.ID, expected: nil},
+		{cursor: "not base64!", expected: errInvalidCursor},
+		{cursor: encode(chirp.CreatedAt.Format(time.RFC3339Nano) + chirp.ID.String()), expected: errInvalidCursor},
+		{cursor: encode("yesterday|" + chirp.ID.String()), expected: errInvalidCursor},
+		{cursor: encode(chirp.CreatedAt.Format(time.RFC3339Nano) + "|not-a-uuid"), expected: errInvalidCursor},
+	}
+
+	for i, c := range cases {
+		createdAt, chirpID, err := decodeTimelineCursor(c.cursor)
+		if !errors.Is(err, c.expected) {
+			t.Errorf("Test nr: %d: Expected error %v, got %v", i, c.expected, err)
+			continue
+		}
+		if !createdAt.Equal(c.createdAt) || chirpID != c.chirpID {
+			t.Errorf("Test nr: %d: Expected %v %v, got %v %v", i, c.createdAt, c.chirpID, createdAt, chirpID)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/auth"
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+const (
+	personalAccessTokenDefaultExpiration = 30 * 24 * time.Hour
+	personalAccessTokenMaxDays           = 365
+)
+
+type personalAccessTokenView struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func newPersonalAccessTokenView(token database.PersonalAccessToken) personalAccessTokenView {
+	view := personalAccessTokenView{
+		ID:        token.ID,
+		Name:      token.Name,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+	}
+	if token.LastUsedAt.Valid {
+		view.LastUsedAt = &token.LastUsedAt.Time
+	}
+	return view
+}
+
+func createPersonalAccessToken(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reqBody struct {
+			Name string `json:"name"`
+			// ExpiresInDays defaults to 30 days and is capped at a year.
+			ExpiresInDays int      `json:"expires_in_days"`
+			Scopes        []string `json:"scopes"`
+		}
+		type resBody struct {
+			personalAccessTokenView
+			Token string `json:"token"`
+		}
+		// Personal access tokens cannot be used to mint more tokens.
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if len(req.Name) == 0 || len(req.Name) > 100 {
+			errorMessage := "Name must be between 1 and 100 characters long"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if len(req.Scopes) == 0 {
+			errorMessage := "At least one scope is required"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		for _, scope := range req.Scopes {
+			if !auth.ValidScope(scope) {
+				errorMessage := fmt.Sprintf("Unknown scope: %s", scope)
+				responseError(w, errorMessage, 400)
+				return
+			}
+		}
+		// The range is checked on the days so a huge value cannot overflow
+		// the duration into an accepted one.
+		if req.ExpiresInDays < 0 || req.ExpiresInDays > personalAccessTokenMaxDays {
+			errorMessage := fmt.Sprintf("Expiry must be between 1 and %d days", personalAccessTokenMaxDays)
+			responseError(w, errorMessage, 400)
+			return
+		}
+		expiresIn := personalAccessTokenDefaultExpiration
+		if req.ExpiresInDays != 0 {
+			expiresIn = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		}
+		token, err := auth.MakePersonalAccessToken()
+		if err != nil {
+			errorMessage := "Cannot create token"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		slices.Sort(req.Scopes)
+		personalAccessToken, err := cfg.db.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+			TokenHash: auth.HashToken(token, cfg.tokenHashSecret),
+			Name:      req.Name,
+			Scopes:    slices.Compact(req.Scopes),
+			ExpiresAt: time.Now().Add(expiresIn),
+			UserID:    userID,
+		})
+		if err != nil {
+			log.Printf("Error creating personal access token: %s\n", err)
+			errorMessage := "Cannot create token"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := resBody{
+			personalAccessTokenView: newPersonalAccessTokenView(personalAccessToken),
+			Token:                   token,
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(201)
+		w.Write(data)
+	}
+}
+
+func getPersonalAccessTokens(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		tokens, err := cfg.db.GetUserPersonalAccessTokens(r.Context(), userID)
+		if err != nil {
+			errorMessage := "Cannot retrieve tokens"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		res := []personalAccessTokenView{}
+		for _, token := range tokens {
+			res = append(res, newPersonalAccessTokenView(token))
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+func deletePersonalAccessToken(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			errorMessage := "Missing header"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		userID, err := validateUserAccessToken(r.Context(), cfg, accessToken)
+		if err != nil {
+			responseTokenError(w, err)
+			return
+		}
+		tokenID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			errorMessage := "Invalid token ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		revoked, err := cfg.db.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+			ID:     tokenID,
+			UserID: userID,
+		})
+		if err != nil {
+			errorMessage := "Cannot revoke token"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if revoked == 0 {
+			errorMessage := "Token was not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		w.WriteHeader(204)
+	}
+}
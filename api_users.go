@@ -1,14 +1,21 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/hrncacz/go-chirpy/internal/auth"
 	"github.com/hrncacz/go-chirpy/internal/database"
+	"github.com/hrncacz/go-chirpy/internal/handle"
 )
 
 func createUser(cfg *apiConfig) http.HandlerFunc {
@@ -19,11 +26,12 @@ func createUser(cfg *apiConfig) http.HandlerFunc {
 		}
 
 		type resBody struct {
-			ID          uuid.UUID `json:"id"`
-			CreatedAt   time.Time `json:"created_at"`
-			UpdatedAt   time.Time `json:"updated_at"`
-			Email       string    `json:"email"`
-			IsChirpyRed bool      `json:"is_chirpy_red"`
+			ID            uuid.UUID `json:"id"`
+			CreatedAt     time.Time `json:"created_at"`
+			UpdatedAt     time.Time `json:"updated_at"`
+			Email         string    `json:"email"`
+			EmailVerified bool      `json:"email_verified"`
+			IsChirpyRed   bool      `json:"is_chirpy_red"`
 		}
 
 		decoder := json.NewDecoder(r.Body)
@@ -36,8 +44,16 @@ func createUser(cfg *apiConfig) http.HandlerFunc {
 			responseError(w, errorMessage, http.StatusBadRequest)
 			return
 		}
+		if err := validateEmail(req.Email); err != nil {
+			errorMessage := "Invalid email address"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		if !checkPasswordPolicy(w, cfg, req.Password, req.Email) {
+			return
+		}
 
-		hashedPassword, err := auth.HashPassword(req.Password)
+		hashedPassword, err := cfg.passwords.Hash(req.Password)
 		if err != nil {
 			fmt.Println(err)
 			errorMessage := "Cannot create user"
@@ -55,12 +71,16 @@ func createUser(cfg *apiConfig) http.HandlerFunc {
 			responseError(w, errorMessage, 500)
 			return
 		}
+		if err := sendEmailVerification(r.Context(), cfg, user.ID, user.Email); err != nil {
+			log.Printf("Error creating verification token: %s\n", err)
+		}
 		res := resBody{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
+			ID:            user.ID,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerifiedAt.Valid,
+			IsChirpyRed:   user.IsChirpyRed,
 		}
 		data, err := json.Marshal(res)
 		if err != nil {
@@ -75,32 +95,97 @@ func createUser(cfg *apiConfig) http.HandlerFunc {
 
 }
 
+// confirmIdentity checks the current password and, when TOTP is enabled,
+// the second factor before credentials are changed. Failures count towards
+// the login lockout. It answers the request itself and returns false when
+// the caller could not be confirmed.
+func confirmIdentity(w http.ResponseWriter, r *http.Request, cfg *apiConfig, user database.User, password, code, recoveryCode string) bool {
+	accountKey := loginAccountKey(user.Email)
+	if !allowLoginAttempt(w, r, cfg, accountKey) {
+		return false
+	}
+	_, err := cfg.passwords.Verify(password, user.HashedPassword)
+	if errors.Is(err, auth.ErrPasswordDisabled) {
+		errorMessage := "Account has no password, set one with a password reset first"
+		responseError(w, errorMessage, 403)
+		return false
+	}
+	if err != nil {
+		failLoginAttempt(r, cfg, accountKey)
+		errorMessage := "Incorrect password"
+		responseError(w, errorMessage, 401)
+		return false
+	}
+	if user.TotpEnabledAt.Valid {
+		ok, err := checkSecondFactor(r.Context(), cfg, user, code, recoveryCode)
+		if err != nil {
+			errorMessage := "Cannot verify code"
+			responseError(w, errorMessage, 500)
+			return false
+		}
+		if !ok {
+			failLoginAttempt(r, cfg, accountKey)
+			errorMessage := "Invalid code"
+			responseError(w, errorMessage, 401)
+			return false
+		}
+	}
+	succeedLoginAttempt(r, cfg, accountKey)
+	return true
+}
+
+// changeEmailPassword needs the current password, and the second factor when
+// TOTP is enabled, besides a signed in session.
 func changeEmailPassword(cfg *apiConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		type reqBody struct {
-			Email    string `json:"email"`
-			Password string `json:"password"`
+			Email           string `json:"email"`
+			Password        string `json:"password"`
+			CurrentPassword string `json:"current_password"`
+			Code            string `json:"code"`
+			RecoveryCode    string `json:"recovery_code"`
 		}
-		accessToken, err := auth.GetBearerToken(r.Header)
-		if err != nil {
-			errorMessage := "Missing header"
+		type resBody struct {
+			ID            uuid.UUID `json:"id"`
+			CreatedAt     time.Time `json:"created_at"`
+			UpdatedAt     time.Time `json:"updated_at"`
+			Email         string    `json:"email"`
+			EmailVerified bool      `json:"email_verified"`
+			PendingEmail  string    `json:"pending_email,omitempty"`
+			IsChirpyRed   bool      `json:"is_chirpy_red"`
+		}
+		userID := principalFromContext(r.Context()).UserID
+		decoder := json.NewDecoder(r.Body)
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
 			responseError(w, errorMessage, 401)
 			return
 		}
-		userID, err := auth.ValidateJWT(accessToken, cfg.jwtSignString)
+		if err := validateEmail(req.Email); err != nil {
+			errorMessage := "Invalid email address"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		current, err := cfg.db.GetUserByID(r.Context(), userID)
 		if err != nil {
-			errorMessage := "Invalid JWT"
+			errorMessage := "User not found"
 			responseError(w, errorMessage, 401)
 			return
 		}
-		decoder := json.NewDecoder(r.Body)
-		req := reqBody{}
-		if err = decoder.Decode(&req); err != nil {
-			errorMessage := "Invalid body"
-			responseError(w, errorMessage, 401)
+		if !confirmIdentity(w, r, cfg, current, req.CurrentPassword, req.Code, req.RecoveryCode) {
 			return
 		}
-		hashedPassword, err := auth.HashPassword(req.Password)
+		// A new address only replaces the current one once it is verified.
+		if owner, err := cfg.db.GetUserByEmail(r.Context(), req.Email); err == nil && owner.ID != userID {
+			errorMessage := "Email address is already in use"
+			responseError(w, errorMessage, 409)
+			return
+		}
+		if !checkPasswordPolicy(w, cfg, req.Password, req.Email) {
+			return
+		}
+		hashedPassword, err := cfg.passwords.Hash(req.Password)
 		if err != nil {
 			errorMessage := "Invalid password"
 			responseError(w, errorMessage, 500)
@@ -116,7 +201,23 @@ func changeEmailPassword(cfg *apiConfig) http.HandlerFunc {
 			responseError(w, errorMessage, 401)
 			return
 		}
-		data, err := json.Marshal(user)
+		if user.PendingEmail.Valid {
+			if err := sendEmailVerification(r.Context(), cfg, user.ID, user.PendingEmail.String); err != nil {
+				errorMessage := "Cannot send verification email"
+				responseError(w, errorMessage, 500)
+				return
+			}
+		}
+		res := resBody{
+			ID:            user.ID,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerifiedAt.Valid,
+			PendingEmail:  user.PendingEmail.String,
+			IsChirpyRed:   user.IsChirpyRed,
+		}
+		data, err := json.Marshal(res)
 		if err != nil {
 			errorMessage := "Unable to marshal response data"
 			responseError(w, errorMessage, 401)
@@ -128,3 +229,314 @@ func changeEmailPassword(cfg *apiConfig) http.HandlerFunc {
 
 	}
 }
+
+// Profile field limits in characters.
+const (
+	displayNameMaxLength = 50
+	bioMaxLength         = 160
+	locationMaxLength    = 30
+	profileURLMaxLength  = 200
+)
+
+// userProfileView is the public part of a user.
+type userProfileView struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	Location    string    `json:"location"`
+	Website     string    `json:"website"`
+
+	FollowerCount  int32 `json:"follower_count"`
+	FollowingCount int32 `json:"following_count"`
+}
+
+func newUserProfileView(user database.User) userProfileView {
+	return userProfileView{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+		Location:    user.Location,
+		Website:     user.Website,
+
+		FollowerCount:  user.FollowerCount,
+		FollowingCount: user.FollowingCount,
+	}
+}
+
+// meView is the profile as its owner sees it.
+type meView struct {
+	userProfileView
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Email               string     `json:"email"`
+	EmailVerified       bool       `json:"email_verified"`
+	PendingEmail        string     `json:"pending_email,omitempty"`
+	IsChirpyRed         bool       `json:"is_chirpy_red"`
+	Role                string     `json:"role"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+func newMeView(user database.User) meView {
+	view := meView{
+		userProfileView: newUserProfileView(user),
+		UpdatedAt:       user.UpdatedAt,
+		Email:           user.Email,
+		EmailVerified:   user.EmailVerifiedAt.Valid,
+		PendingEmail:    user.PendingEmail.String,
+		IsChirpyRed:     user.IsChirpyRed,
+		Role:            user.Role,
+	}
+	if user.DeletionScheduledAt.Valid {
+		view.DeletionScheduledAt = &user.DeletionScheduledAt.Time
+	}
+	return view
+}
+
+func responseMe(w http.ResponseWriter, user database.User) {
+	data, err := json.Marshal(newMeView(user))
+	if err != nil {
+		errorMessage := "Cannot marshal response"
+		responseError(w, errorMessage, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+func getMe(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := cfg.db.GetUserByID(r.Context(), principalFromContext(r.Context()).UserID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		responseMe(w, user)
+	}
+}
+
+// getUserProfile hides accounts that are scheduled for deletion.
+func getUserProfile(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			errorMessage := "Invalid user ID"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil || user.DeletionScheduledAt.Valid {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		data, err := json.Marshal(newUserProfileView(user))
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// getUserList serves the lists under /api/users/{id}/ by name. They share a
+// route because ServeMux cannot tell /api/users/{id}/likes apart from
+// /api/users/by-handle/{handle}.
+func getUserList(lists map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, ok := lists[r.PathValue("list")]
+		if !ok {
+			errorMessage := "Not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		list(w, r)
+	}
+}
+
+// updateMe changes only the fields present in the body. An empty string
+// clears a profile field. A new email address, as with PUT /api/users, only
+// replaces the current one once it is verified.
+func updateMe(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type reqBody struct {
+			Email       *string `json:"email"`
+			Password    *string `json:"password"`
+			Handle      *string `json:"handle"`
+			DisplayName *string `json:"display_name"`
+			Bio         *string `json:"bio"`
+			AvatarURL   *string `json:"avatar_url"`
+			Location    *string `json:"location"`
+			Website     *string `json:"website"`
+			// CurrentPassword, and Code or RecoveryCode when TOTP is
+			// enabled, are needed to change the email or password.
+			CurrentPassword string `json:"current_password"`
+			Code            string `json:"code"`
+			RecoveryCode    string `json:"recovery_code"`
+		}
+		p := principalFromContext(r.Context())
+		userID := p.UserID
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		req := reqBody{}
+		if err := decoder.Decode(&req); err != nil {
+			errorMessage := "Invalid body"
+			responseError(w, errorMessage, 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			errorMessage := "User not found"
+			responseError(w, errorMessage, 404)
+			return
+		}
+		profile := database.UpdateUserProfileParams{ID: userID}
+		fields := []struct {
+			name      string
+			value     *string
+			maxLength int
+			isURL     bool
+			target    *sql.NullString
+		}{
+			{"display_name", req.DisplayName, displayNameMaxLength, false, &profile.DisplayName},
+			{"bio", req.Bio, bioMaxLength, false, &profile.Bio},
+			{"avatar_url", req.AvatarURL, profileURLMaxLength, true, &profile.AvatarUrl},
+			{"location", req.Location, locationMaxLength, false, &profile.Location},
+			{"website", req.Website, profileURLMaxLength, true, &profile.Website},
+		}
+		profileChanged := false
+		for _, field := range fields {
+			if field.value == nil {
+				continue
+			}
+			value := strings.TrimSpace(*field.value)
+			if utf8.RuneCountInString(value) > field.maxLength {
+				errorMessage := fmt.Sprintf("%s must be at most %d characters", field.name, field.maxLength)
+				responseError(w, errorMessage, 400)
+				return
+			}
+			if field.isURL && len(value) > 0 && !validProfileURL(value) {
+				errorMessage := fmt.Sprintf("%s must be an http or https URL", field.name)
+				responseError(w, errorMessage, 400)
+				return
+			}
+			*field.target = sql.NullString{String: value, Valid: true}
+			profileChanged = true
+		}
+		if req.Email != nil {
+			if err := validateEmail(*req.Email); err != nil {
+				errorMessage := "Invalid email address"
+				responseError(w, errorMessage, 400)
+				return
+			}
+			if owner, err := cfg.db.GetUserByEmail(r.Context(), *req.Email); err == nil && owner.ID != userID {
+				errorMessage := "Email address is already in use"
+				responseError(w, errorMessage, 409)
+				return
+			}
+		}
+		if req.Handle != nil {
+			err := handle.Validate(*req.Handle)
+			if err == nil {
+				err = checkHandleAvailable(r.Context(), cfg.db, userID, *req.Handle)
+			}
+			if responseHandleError(w, err) {
+				return
+			}
+		}
+		var hashedPassword string
+		if req.Password != nil {
+			email := user.Email
+			if req.Email != nil {
+				email = *req.Email
+			}
+			if !checkPasswordPolicy(w, cfg, *req.Password, email) {
+				return
+			}
+			hashedPassword, err = cfg.passwords.Hash(*req.Password)
+			if err != nil {
+				errorMessage := "Cannot update user"
+				responseError(w, errorMessage, 500)
+				return
+			}
+		}
+		// Profile fields may be changed with a profile:write token, the
+		// credentials only from a session, as with PUT /api/users.
+		if req.Email != nil || req.Password != nil {
+			if !p.Session {
+				errorMessage := "Credentials can only be changed from a signed in session"
+				responseError(w, errorMessage, 403)
+				return
+			}
+			if !confirmIdentity(w, r, cfg, user, req.CurrentPassword, req.Code, req.RecoveryCode) {
+				return
+			}
+		}
+		// All changes are stored together or not at all.
+		err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			if req.Handle != nil {
+				user, err = setHandle(r.Context(), cfg, q, user, *req.Handle)
+				if err != nil {
+					return err
+				}
+			}
+			if profileChanged {
+				user, err = q.UpdateUserProfile(r.Context(), profile)
+				if err != nil {
+					return err
+				}
+			}
+			if req.Email != nil {
+				user, err = q.UpdateUserEmail(r.Context(), database.UpdateUserEmailParams{
+					ID:    userID,
+					Email: *req.Email,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if req.Password != nil {
+				return q.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+					ID:             userID,
+					HashedPassword: hashedPassword,
+				})
+			}
+			return nil
+		})
+		if errors.Is(err, errHandleTaken) {
+			responseHandleError(w, err)
+			return
+		}
+		if err != nil {
+			errorMessage := "Cannot update user"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		if req.Email != nil && user.PendingEmail.Valid {
+			if err := sendEmailVerification(r.Context(), cfg, user.ID, user.PendingEmail.String); err != nil {
+				errorMessage := "Cannot send verification email"
+				responseError(w, errorMessage, 500)
+				return
+			}
+		}
+		responseMe(w, user)
+	}
+}
+
+func validProfileURL(value string) bool {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && len(parsed.Host) > 0
+}
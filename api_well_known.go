@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func getJWKS(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(cfg.jwtKeys.JWKS())
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
+
+// getOpenIDConfiguration publishes the OpenID Connect discovery document.
+// Third parties can only verify ID tokens when the keyring holds asymmetric
+// keys, since HMAC secrets are never published.
+func getOpenIDConfiguration(cfg *apiConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type resBody struct {
+			Issuer                            string   `json:"issuer"`
+			AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+			TokenEndpoint                     string   `json:"token_endpoint"`
+			IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+			RevocationEndpoint                string   `json:"revocation_endpoint"`
+			JWKSURI                           string   `json:"jwks_uri"`
+			ScopesSupported                   []string `json:"scopes_supported"`
+			ResponseTypesSupported            []string `json:"response_types_supported"`
+			GrantTypesSupported               []string `json:"grant_types_supported"`
+			SubjectTypesSupported             []string `json:"subject_types_supported"`
+			IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+			TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+			CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+			ClaimsSupported                   []string `json:"claims_supported"`
+		}
+		res := resBody{
+			Issuer:                            cfg.baseURL,
+			AuthorizationEndpoint:             cfg.baseURL + "/oauth/authorize",
+			TokenEndpoint:                     cfg.baseURL + "/oauth/token",
+			IntrospectionEndpoint:             cfg.baseURL + "/api/introspect",
+			RevocationEndpoint:                cfg.baseURL + "/api/revoke",
+			JWKSURI:                           cfg.baseURL + "/.well-known/jwks.json",
+			ScopesSupported:                   oauthScopes(),
+			ResponseTypesSupported:            []string{"code"},
+			GrantTypesSupported:               oauthGrantTypes,
+			SubjectTypesSupported:             []string{"public"},
+			IDTokenSigningAlgValuesSupported:  cfg.jwtKeys.Algorithms(),
+			TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+			CodeChallengeMethodsSupported:     []string{"S256"},
+			ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified"},
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			errorMessage := "Cannot marshal response"
+			responseError(w, errorMessage, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(200)
+		w.Write(data)
+	}
+}
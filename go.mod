@@ -3,12 +3,12 @@ module github.com/hrncacz/go-chirpy
 go 1.24.6
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/google/uuid v1.6.0
 	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.42.0
 )
 
-require (
-	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
-	golang.org/x/crypto v0.42.0 // indirect
-)
+require github.com/joho/godotenv v1.5.1
+
+require golang.org/x/sys v0.36.0 // indirect
@@ -1,73 +1,36 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// HashPassword hashes with DefaultPasswordHashing.
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedPassword), nil
+	return DefaultPasswordHashing().Hash(password)
 }
 
 func CheckPasswordHash(password, hash string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	if err != nil {
-		return err
-	}
-	return nil
+	_, err := DefaultPasswordHashing().Verify(password, hash)
+	return err
 }
 
+// MakeJWT signs a token with a single HS256 secret. Servers with rotating
+// keys use Keyring.MakeJWT instead.
 func MakeJWT(userID uuid.UUID, jwtSignString string, expiresIn time.Duration) (string, error) {
-	currentTime := time.Now()
-	expirationTime := currentTime.Add(expiresIn)
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(currentTime),
-		ExpiresAt: jwt.NewNumericDate(expirationTime),
-		Subject:   userID.String(),
-	}
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		claims,
-	)
-	jwtString, err := token.SignedString([]byte(jwtSignString))
-	if err != nil {
-		return "", errors.New("error while creating Signed jwt string")
-	}
-	return jwtString, nil
+	return NewKeyring(NewHMACKey("default", []byte(jwtSignString))).MakeJWT(userID, expiresIn)
 }
 
 func ValidateJWT(tokenString, jwtSignString string) (uuid.UUID, error) {
-	claims := jwt.MapClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		return []byte(jwtSignString), nil
-	})
-	if err != nil {
-		return uuid.Nil, errors.New("invalid token")
-	}
-	fmt.Println(claims)
-	userID, err := token.Claims.GetSubject()
-	if err != nil {
-		return uuid.Nil, err
-	}
-	parsedUserID, err := uuid.Parse(userID)
-	if err != nil {
-		return uuid.Nil, err
-	}
-	return parsedUserID, err
+	return NewKeyring(NewHMACKey("default", []byte(jwtSignString))).ValidateJWT(tokenString)
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -81,11 +44,28 @@ func GetBearerToken(headers http.Header) (string, error) {
 }
 
 func MakeRefreshToken() (string, error) {
+	return MakeToken()
+}
+
+// MakeToken returns a random hex encoded 256 bit token.
+func MakeToken() (string, error) {
 	randomData := make([]byte, 32)
-	rand.Read(randomData)
+	if _, err := rand.Read(randomData); err != nil {
+		return "", err
+	}
 	randomString := hex.EncodeToString(randomData)
 	return randomString, nil
 }
+
+// HashToken returns the keyed hash under which an opaque token, such as a
+// refresh or password reset token, is stored, so a leaked table does not
+// expose usable tokens.
+func HashToken(token, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	token := headers.Get("Authorization")
 	if len(token) == 0 {
@@ -124,3 +124,79 @@ func TestGetBearer(t *testing.T) {
 		}
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	cases := []struct {
+		token          string
+		secret         string
+		compareToken   string
+		compareSecret  string
+		expectedEquals bool
+	}{
+		{
+			token:          "token",
+			secret:         "secret",
+			compareToken:   "token",
+			compareSecret:  "secret",
+			expectedEquals: true,
+		},
+		{
+			token:          "token",
+			secret:         "secret",
+			compareToken:   "other token",
+			compareSecret:  "secret",
+			expectedEquals: false,
+		},
+		{
+			token:          "token",
+			secret:         "secret",
+			compareToken:   "token",
+			compareSecret:  "other secret",
+			expectedEquals: false,
+		},
+	}
+
+	for _, c := range cases {
+		hash := HashToken(c.token, c.secret)
+		if hash == c.token {
+			t.Errorf("Hash must not equal the plain token: %s", hash)
+			continue
+		}
+		compareHash := HashToken(c.compareToken, c.compareSecret)
+		if (hash == compareHash) != c.expectedEquals {
+			t.Errorf("Unexpected hash comparison result:\n\tHash: %s\n\tCompared hash: %s", hash, compareHash)
+			continue
+		}
+	}
+}
+
+func TestBearerTokenKind(t *testing.T) {
+	personalAccessToken, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatalf("Error while creating personal access token: %s", err)
+	}
+	jwtToken, err := MakeJWT(uuid.New(), "secret", time.Minute)
+	if err != nil {
+		t.Fatalf("Error while creating JWT: %s", err)
+	}
+
+	cases := []struct {
+		token    string
+		expected TokenKind
+	}{
+		{
+			token:    personalAccessToken,
+			expected: TokenKindPersonalAccess,
+		},
+		{
+			token:    jwtToken,
+			expected: TokenKindJWT,
+		},
+	}
+
+	for i, c := range cases {
+		if kind := BearerTokenKind(c.token); kind != c.expected {
+			t.Errorf("Test nr: %d: Expected token kind %d, got %d", i, c.expected, kind)
+		}
+	}
+}
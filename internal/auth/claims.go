@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleModerator || role == RoleAdmin
+}
+
+// Claims are the claims of an access token. Scope is a space separated list
+// as in RFC 8693.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role  string `json:"role,omitempty"`
+	Scope string `json:"scope,omitempty"`
+	// ClientID names the OAuth client the token was issued to. It is empty
+	// for tokens from the first-party login.
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// GetRole returns the role claim. Tokens without one belong to plain users.
+func (c *Claims) GetRole() string {
+	if len(c.Role) == 0 {
+		return RoleUser
+	}
+	return c.Role
+}
+
+// GetScopes returns the scope claim. First-party tokens issued before scopes
+// were added carry none and keep the full rights they had.
+func (c *Claims) GetScopes() []string {
+	if len(c.Scope) == 0 && len(c.ClientID) == 0 {
+		return slices.Clone(Scopes)
+	}
+	return strings.Fields(c.Scope)
+}
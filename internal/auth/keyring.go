@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// SigningKey is a single JWT key identified by its kid.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// VerifyUntil limits how long a key that is no longer active is accepted
+	// for verification. The zero value means no limit.
+	VerifyUntil time.Time
+	signKey     any
+	verifyKey   any
+}
+
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+func NewRSAKey(id string, privateKey *rsa.PrivateKey) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodRS256,
+		signKey:   privateKey,
+		verifyKey: &privateKey.PublicKey,
+	}
+}
+
+func NewEd25519Key(id string, privateKey ed25519.PrivateKey) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodEdDSA,
+		signKey:   privateKey,
+		verifyKey: privateKey.Public(),
+	}
+}
+
+// ParsePrivateKeyPEM reads an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8)
+// private key and picks the matching signing method.
+func ParsePrivateKeyPEM(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewRSAKey(id, privateKey), nil
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return NewRSAKey(id, privateKey), nil
+	case ed25519.PrivateKey:
+		return NewEd25519Key(id, privateKey), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+}
+
+func (key *SigningKey) usableAt(now time.Time) bool {
+	return key.VerifyUntil.IsZero() || now.Before(key.VerifyUntil)
+}
+
+// Keyring signs new tokens with its active key and verifies tokens signed by
+// any key it still holds.
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	// unversioned verifies tokens issued before kid headers were added.
+	unversioned *SigningKey
+}
+
+func NewKeyring(active *SigningKey, verifyOnly ...*SigningKey) *Keyring {
+	keyring := &Keyring{
+		active: active,
+		keys:   map[string]*SigningKey{active.ID: active},
+	}
+	for _, key := range verifyOnly {
+		keyring.keys[key.ID] = key
+	}
+	return keyring
+}
+
+// AllowUnversioned accepts tokens without a kid header, verified with the
+// given key, until the given time.
+func (k *Keyring) AllowUnversioned(key *SigningKey, until time.Time) {
+	k.unversioned = &SigningKey{
+		ID:          key.ID,
+		Method:      key.Method,
+		VerifyUntil: until,
+		signKey:     key.signKey,
+		verifyKey:   key.verifyKey,
+	}
+}
+
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.signKey)
+}
+
+func (k *Keyring) keyFunc(token *jwt.Token) (any, error) {
+	var key *SigningKey
+	kid, ok := token.Header["kid"].(string)
+	if ok {
+		key = k.keys[kid]
+	} else {
+		key = k.unversioned
+	}
+	if key == nil || !key.usableAt(time.Now()) {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrTokenAlgorithm
+	}
+	return key.verifyKey, nil
+}
+
+func (k *Keyring) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.MakeAccessToken(userID, "", nil, expiresIn)
+}
+
+// MakeAccessToken signs a token carrying the user's role and granted scopes.
+// An empty role or scope list leaves the claim out.
+func (k *Keyring) MakeAccessToken(userID uuid.UUID, role string, scopes []string, expiresIn time.Duration) (string, error) {
+	claims := NewClaims(userID.String(), expiresIn)
+	claims.Role = role
+	claims.Scope = strings.Join(scopes, " ")
+	jwtString, err := k.Sign(claims)
+	if err != nil {
+		return "", errors.New("error while creating Signed jwt string")
+	}
+	return jwtString, nil
+}
+
+// ValidateJWT checks the token with DefaultValidatorOptions.
+func (k *Keyring) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	return NewValidator(k, DefaultValidatorOptions(k)).ValidateJWT(tokenString)
+}
+
+// Algorithms returns the signing methods of all keys in the keyring.
+func (k *Keyring) Algorithms() []string {
+	algorithms := []string{}
+	keys := append(slices.Collect(maps.Values(k.keys)), k.unversioned)
+	for _, key := range keys {
+		if key != nil && !slices.Contains(algorithms, key.Method.Alg()) {
+			algorithms = append(algorithms, key.Method.Alg())
+		}
+	}
+	return algorithms
+}
+
+// JWK is the public part of a signing key as published in a JWK Set.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that can currently verify tokens. Shared
+// HMAC secrets are never published.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	now := time.Now()
+	for _, key := range k.keys {
+		if !key.usableAt(now) {
+			continue
+		}
+		jwk := JWK{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(a, b int) bool { return set.Keys[a].KeyID < set.Keys[b].KeyID })
+	return set
+}
+
+// LoadKeyringFile builds a keyring from a JSON file of the form
+//
+//	{
+//	  "active": "2026-10",
+//	  "keys": [
+//	    {"kid": "2026-10", "private_key_file": "keys/2026-10.pem"},
+//	    {"kid": "2026-04", "private_key_file": "keys/2026-04.pem", "verify_until": "2026-11-01T00:00:00Z"}
+//	  ]
+//	}
+//
+// Relative key paths are resolved against the directory of the file.
+func LoadKeyringFile(path string) (*Keyring, error) {
+	type keyConfig struct {
+		KeyID          string    `json:"kid"`
+		PrivateKeyFile string    `json:"private_key_file"`
+		VerifyUntil    time.Time `json:"verify_until"`
+	}
+	type keyringConfig struct {
+		Active string      `json:"active"`
+		Keys   []keyConfig `json:"keys"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := keyringConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	var active *SigningKey
+	verifyOnly := []*SigningKey{}
+	for _, c := range config.Keys {
+		keyPath := c.PrivateKeyFile
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(filepath.Dir(path), keyPath)
+		}
+		pemData, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParsePrivateKeyPEM(c.KeyID, pemData)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", c.KeyID, err)
+		}
+		if c.KeyID == config.Active {
+			active = key
+			continue
+		}
+		key.VerifyUntil = c.VerifyUntil
+		verifyOnly = append(verifyOnly, key)
+	}
+	if active == nil {
+		return nil, fmt.Errorf("active key %q not found", config.Active)
+	}
+	return NewKeyring(active, verifyOnly...), nil
+}
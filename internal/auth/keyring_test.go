@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error while generating RSA key: %s", err)
+	}
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error while generating Ed25519 key: %s", err)
+	}
+	hmacKey := NewHMACKey("hs", []byte("hello world"))
+	rsaKey := NewRSAKey("rs", rsaPrivateKey)
+	edKey := NewEd25519Key("ed", edPrivateKey)
+
+	cases := []struct {
+		signingKeyring    *Keyring
+		validatingKeyring *Keyring
+		expected          bool
+	}{
+		{
+			signingKeyring:    NewKeyring(rsaKey),
+			validatingKeyring: NewKeyring(rsaKey),
+			expected:          true,
+		},
+		{
+			signingKeyring:    NewKeyring(edKey),
+			validatingKeyring: NewKeyring(rsaKey, edKey),
+			expected:          true,
+		},
+		{
+			signingKeyring:    NewKeyring(hmacKey),
+			validatingKeyring: NewKeyring(edKey, &SigningKey{ID: "hs", Method: hmacKey.Method, VerifyUntil: time.Now().Add(time.Hour), verifyKey: hmacKey.verifyKey}),
+			expected:          true,
+		},
+		{
+			signingKeyring:    NewKeyring(hmacKey),
+			validatingKeyring: NewKeyring(edKey, &SigningKey{ID: "hs", Method: hmacKey.Method, VerifyUntil: time.Now().Add(-time.Hour), verifyKey: hmacKey.verifyKey}),
+			expected:          false,
+		},
+		{
+			signingKeyring:    NewKeyring(edKey),
+			validatingKeyring: NewKeyring(rsaKey),
+			expected:          false,
+		},
+		{
+			signingKeyring:    NewKeyring(NewHMACKey("rs", []byte("hello world"))),
+			validatingKeyring: NewKeyring(rsaKey),
+			expected:          false,
+		},
+	}
+
+	for i, c := range cases {
+		userID := uuid.New()
+		token, err := c.signingKeyring.MakeJWT(userID, time.Minute)
+		if err != nil {
+			t.Errorf("Test nr: %d: Error while creating JWT: %s", i, err)
+			continue
+		}
+		validatedUserID, err := c.validatingKeyring.ValidateJWT(token)
+		if (err == nil) != c.expected {
+			t.Errorf("Test nr: %d: Unexpected validation result: %v", i, err)
+			continue
+		}
+		if c.expected && validatedUserID != userID {
+			t.Errorf("Test nr: %d: Returned user ID is not same as input user ID:\n\tInput ID: %s\n\tOutput ID: %s", i, userID, validatedUserID)
+			continue
+		}
+	}
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error while generating RSA key: %s", err)
+	}
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error while generating Ed25519 key: %s", err)
+	}
+	expiredKey := NewEd25519Key("expired", edPrivateKey)
+	expiredKey.VerifyUntil = time.Now().Add(-time.Hour)
+	keyring := NewKeyring(
+		NewEd25519Key("ed", edPrivateKey),
+		NewRSAKey("rs", rsaPrivateKey),
+		NewHMACKey("hs", []byte("hello world")),
+		expiredKey,
+	)
+
+	set := keyring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("Expected 2 published keys, got %d", len(set.Keys))
+	}
+	if set.Keys[0].KeyID != "ed" || set.Keys[0].KeyType != "OKP" || set.Keys[0].Algorithm != "EdDSA" {
+		t.Errorf("Unexpected Ed25519 JWK: %+v", set.Keys[0])
+	}
+	if set.Keys[1].KeyID != "rs" || set.Keys[1].KeyType != "RSA" || set.Keys[1].E != "AQAB" {
+		t.Errorf("Unexpected RSA JWK: %+v", set.Keys[1])
+	}
+}
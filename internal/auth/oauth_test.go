@@ -0,0 +1,40 @@
+package auth
+
+import "testing"
+
+func TestVerifyPKCE(t *testing.T) {
+	// Example from RFC 7636 appendix B.
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	cases := []struct {
+		verifier string
+		expected bool
+	}{
+		{
+			verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk",
+			expected: true,
+		},
+		{
+			verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXK",
+			expected: false,
+		},
+		{
+			verifier: "too-short",
+			expected: false,
+		},
+	}
+
+	for i, c := range cases {
+		if VerifyPKCE(c.verifier, challenge) != c.expected {
+			t.Errorf("Test nr: %d: Expected %v for verifier %q", i, c.expected, c.verifier)
+		}
+	}
+}
+
+func TestClientTokenScopes(t *testing.T) {
+	claims := NewClaims("client", 0)
+	claims.ClientID = "client"
+	if scopes := claims.GetScopes(); len(scopes) != 0 {
+		t.Errorf("Expected no scopes for a client token without a scope claim, got %v", scopes)
+	}
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UnsetPassword is the hashed_password default for users created before
+// passwords existed. It never matches any password.
+const UnsetPassword = "unset"
+
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrPasswordDisabled    = errors.New("password login is disabled for this user")
+	ErrPasswordHashUnknown = errors.New("password hash format is not recognized")
+)
+
+// PasswordHasher is one password hashing algorithm.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify checks the password against a hash this hasher recognizes.
+	// needsRehash reports whether the hash was made with weaker parameters
+	// than the hasher currently uses.
+	Verify(password, hash string) (needsRehash bool, err error)
+	Recognizes(hash string) bool
+}
+
+// PasswordHashing hashes new passwords with its current hasher and verifies
+// hashes made by any hasher it still holds.
+type PasswordHashing struct {
+	current PasswordHasher
+	legacy  []PasswordHasher
+}
+
+func NewPasswordHashing(current PasswordHasher, legacy ...PasswordHasher) *PasswordHashing {
+	return &PasswordHashing{
+		current: current,
+		legacy:  legacy,
+	}
+}
+
+// DefaultPasswordHashing writes argon2id hashes and still accepts bcrypt.
+func DefaultPasswordHashing() *PasswordHashing {
+	return NewPasswordHashing(NewArgon2idHasher(DefaultArgon2idParams), NewBcryptHasher(12))
+}
+
+func (p *PasswordHashing) Hash(password string) (string, error) {
+	return p.current.Hash(password)
+}
+
+// Verify checks the password. needsRehash is true when the stored hash should
+// be replaced by a fresh Hash of the same password.
+func (p *PasswordHashing) Verify(password, hash string) (needsRehash bool, err error) {
+	if hash == UnsetPassword || len(hash) == 0 {
+		return false, ErrPasswordDisabled
+	}
+	if p.current.Recognizes(hash) {
+		return p.current.Verify(password, hash)
+	}
+	for _, hasher := range p.legacy {
+		if hasher.Recognizes(hash) {
+			if _, err := hasher.Verify(password, hash); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	return false, ErrPasswordHashUnknown
+}
+
+type Argon2idParams struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the OWASP recommendation of 64 MiB memory.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Validate rejects parameters argon2 cannot hash with. Zero memory,
+// iterations or parallelism make argon2.IDKey panic.
+func (p Argon2idParams) Validate() error {
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return errors.New("argon2id memory, iterations and parallelism must be positive")
+	}
+	return nil
+}
+
+// Argon2idHasher writes hashes in the PHC string format
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.params.Memory,
+		h.params.Iterations,
+		h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(password, hash string) (bool, error) {
+	params, salt, key, err := parseArgon2idHash(hash)
+	if err != nil {
+		return false, err
+	}
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return false, ErrPasswordMismatch
+	}
+	needsRehash := params.Memory < h.params.Memory ||
+		params.Iterations < h.params.Iterations ||
+		params.Parallelism < h.params.Parallelism ||
+		params.SaltLength < h.params.SaltLength ||
+		params.KeyLength < h.params.KeyLength
+	return needsRehash, nil
+}
+
+func (h *Argon2idHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func parseArgon2idHash(hash string) (Argon2idParams, []byte, []byte, error) {
+	params := Argon2idParams{}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrPasswordHashUnknown
+	}
+	version := 0
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrPasswordHashUnknown
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil || params.Validate() != nil {
+		return params, nil, nil, ErrPasswordHashUnknown
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrPasswordHashUnknown
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, ErrPasswordHashUnknown
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+func (h *BcryptHasher) Verify(password, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, ErrPasswordMismatch
+	}
+	if err != nil {
+		return false, err
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, err
+	}
+	return cost < h.cost, nil
+}
+
+func (h *BcryptHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
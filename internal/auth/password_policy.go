@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed breached_passwords.txt
+var embeddedBreachedPasswords string
+
+// BreachedPasswords is a sorted set of SHA-1 hashes of known leaked
+// passwords.
+type BreachedPasswords struct {
+	hashes []string
+}
+
+// DefaultBreachedPasswords returns the small list shipped with the binary.
+func DefaultBreachedPasswords() *BreachedPasswords {
+	breached, _ := ReadBreachedPasswords(strings.NewReader(embeddedBreachedPasswords))
+	return breached
+}
+
+// ReadBreachedPasswords reads one hex SHA-1 hash per line. Lines in the
+// "HASH:COUNT" form of the Pwned Passwords dumps are accepted too.
+func ReadBreachedPasswords(r io.Reader) (*BreachedPasswords, error) {
+	hashes := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		hash, _, _ := strings.Cut(line, ":")
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+		hashes = append(hashes, strings.ToUpper(hash))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Sort(hashes)
+	return &BreachedPasswords{hashes: slices.Compact(hashes)}, nil
+}
+
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	_, found := slices.BinarySearch(b.hashes, strings.ToUpper(hex.EncodeToString(sum[:])))
+	return found
+}
+
+// PasswordViolation describes one policy rule a password failed.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type PasswordPolicy struct {
+	// MinLength and MaxLength count characters, not bytes.
+	MinLength int
+	MaxLength int
+	// RejectEmail refuses passwords containing the email address or its
+	// local part.
+	RejectEmail bool
+	// Breached is optional. A nil list skips the check.
+	Breached *BreachedPasswords
+}
+
+// DefaultPasswordPolicy follows NIST SP 800-63B: at least 8 characters,
+// long passphrases allowed and known leaked passwords refused.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:   8,
+		MaxLength:   128,
+		RejectEmail: true,
+		Breached:    DefaultBreachedPasswords(),
+	}
+}
+
+// Check returns every rule the password fails. An empty result means the
+// password is acceptable.
+func (p PasswordPolicy) Check(password, email string) []PasswordViolation {
+	violations := []PasswordViolation{}
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    "min_length",
+			Message: fmt.Sprintf("Password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    "max_length",
+			Message: fmt.Sprintf("Password must be at most %d characters long", p.MaxLength),
+		})
+	}
+	if p.RejectEmail && containsEmail(password, email) {
+		violations = append(violations, PasswordViolation{
+			Rule:    "contains_email",
+			Message: "Password must not contain the email address",
+		})
+	}
+	if p.Breached != nil && len(password) > 0 && p.Breached.Contains(password) {
+		violations = append(violations, PasswordViolation{
+			Rule:    "breached",
+			Message: "Password appears in a list of leaked passwords",
+		})
+	}
+	return violations
+}
+
+func containsEmail(password, email string) bool {
+	if len(email) == 0 {
+		return false
+	}
+	password = strings.ToLower(password)
+	email = strings.ToLower(email)
+	if strings.Contains(password, email) {
+		return true
+	}
+	localPart, _, _ := strings.Cut(email, "@")
+	// Very short local parts would reject too many unrelated passwords.
+	return utf8.RuneCountInString(localPart) >= 4 && strings.Contains(password, localPart)
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+
+	cases := []struct {
+		password string
+		email    string
+		expected []string
+	}{
+		{
+			password: "correct horse battery staple",
+			email:    "alice@example.com",
+			expected: []string{},
+		},
+		{
+			password: "",
+			email:    "alice@example.com",
+			expected: []string{"min_length"},
+		},
+		{
+			password: strings.Repeat("a", 129),
+			email:    "alice@example.com",
+			expected: []string{"max_length"},
+		},
+		{
+			password: "Alice@Example.com!",
+			email:    "alice@example.com",
+			expected: []string{"contains_email"},
+		},
+		{
+			password: "my name is alice",
+			email:    "alice@example.com",
+			expected: []string{"contains_email"},
+		},
+		{
+			password: "bob is not here",
+			email:    "bob@example.com",
+			expected: []string{},
+		},
+		{
+			password: "password1",
+			email:    "alice@example.com",
+			expected: []string{"breached"},
+		},
+		{
+			password: "qwerty",
+			email:    "alice@example.com",
+			expected: []string{"min_length", "breached"},
+		},
+	}
+
+	for i, c := range cases {
+		rules := []string{}
+		for _, violation := range policy.Check(c.password, c.email) {
+			rules = append(rules, violation.Rule)
+		}
+		if !slices.Equal(rules, c.expected) {
+			t.Errorf("Test nr: %d: Expected violations %v, got %v", i, c.expected, rules)
+		}
+	}
+}
+
+func TestReadBreachedPasswords(t *testing.T) {
+	breached, err := ReadBreachedPasswords(strings.NewReader(
+		"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:3861493\n" +
+			"not a hash\n",
+	))
+	if err != nil {
+		t.Fatalf("Error while reading breached passwords: %s", err)
+	}
+	if !breached.Contains("password") {
+		t.Errorf("Expected %q to be breached", "password")
+	}
+	if breached.Contains("hello world") {
+		t.Errorf("Expected %q not to be breached", "hello world")
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPasswordHashingVerify(t *testing.T) {
+	weakParams := DefaultArgon2idParams
+	weakParams.Memory = 8 * 1024
+	weakParams.Iterations = 1
+	hashing := NewPasswordHashing(NewArgon2idHasher(DefaultArgon2idParams), NewBcryptHasher(12))
+
+	hash := func(hasher PasswordHasher) string {
+		hashedPassword, err := hasher.Hash("hello world")
+		if err != nil {
+			t.Fatalf("Error while hashing password: %s", err)
+		}
+		return hashedPassword
+	}
+
+	cases := []struct {
+		name        string
+		hash        string
+		password    string
+		needsRehash bool
+		expected    error
+	}{
+		{
+			name:     "argon2id",
+			hash:     hash(NewArgon2idHasher(DefaultArgon2idParams)),
+			password: "hello world",
+			expected: nil,
+		},
+		{
+			name:     "argon2id mismatch",
+			hash:     hash(NewArgon2idHasher(DefaultArgon2idParams)),
+			password: "hello there",
+			expected: ErrPasswordMismatch,
+		},
+		{
+			name:        "argon2id with weaker parameters",
+			hash:        hash(NewArgon2idHasher(weakParams)),
+			password:    "hello world",
+			needsRehash: true,
+			expected:    nil,
+		},
+		{
+			name:        "bcrypt",
+			hash:        hash(NewBcryptHasher(4)),
+			password:    "hello world",
+			needsRehash: true,
+			expected:    nil,
+		},
+		{
+			name:     "bcrypt mismatch",
+			hash:     hash(NewBcryptHasher(4)),
+			password: "hello there",
+			expected: ErrPasswordMismatch,
+		},
+		{
+			name:     "unset",
+			hash:     UnsetPassword,
+			password: UnsetPassword,
+			expected: ErrPasswordDisabled,
+		},
+		{
+			name:     "unknown format",
+			hash:     "$1$salt$hash",
+			password: "hello world",
+			expected: ErrPasswordHashUnknown,
+		},
+		{
+			name:     "argon2id with zero parallelism",
+			hash:     "$argon2id$v=19$m=65536,t=3,p=0$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+			password: "hello world",
+			expected: ErrPasswordHashUnknown,
+		},
+		{
+			name:     "argon2id with zero memory",
+			hash:     "$argon2id$v=19$m=0,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+			password: "hello world",
+			expected: ErrPasswordHashUnknown,
+		},
+	}
+
+	for _, c := range cases {
+		needsRehash, err := hashing.Verify(c.password, c.hash)
+		if c.expected == nil && err != nil {
+			t.Errorf("%s: Unexpected error: %s", c.name, err)
+			continue
+		}
+		if c.expected != nil && !errors.Is(err, c.expected) {
+			t.Errorf("%s: Expected error %q, got %v", c.name, c.expected, err)
+			continue
+		}
+		if needsRehash != c.needsRehash {
+			t.Errorf("%s: Expected needsRehash %v, got %v", c.name, c.needsRehash, needsRehash)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	ScopeChirpsRead   = "chirps:read"
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeProfileWrite = "profile:write"
+)
+
+// Scopes lists every scope a personal access token can be granted.
+var Scopes = []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeProfileWrite}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// PersonalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from JWTs without a database lookup.
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+type TokenKind int
+
+const (
+	TokenKindJWT TokenKind = iota
+	TokenKindPersonalAccess
+)
+
+// BearerTokenKind tells a personal access token from a JWT by its prefix.
+func BearerTokenKind(token string) TokenKind {
+	if strings.HasPrefix(token, PersonalAccessTokenPrefix) {
+		return TokenKindPersonalAccess
+	}
+	return TokenKindJWT
+}
+
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + token, nil
+}
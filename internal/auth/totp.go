@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// totpSkew is the number of periods before and after the current one
+	// that are still accepted.
+	totpSkew = 1
+)
+
+var ErrTOTPInvalid = errors.New("invalid TOTP code")
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random 160 bit secret in base32, the format
+// authenticator apps expect.
+func GenerateTOTPSecret() (string, error) {
+	randomData := make([]byte, 20)
+	if _, err := rand.Read(randomData); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(randomData), nil
+}
+
+// TOTPURI returns the otpauth:// URI used to enroll the secret in an
+// authenticator app.
+func TOTPURI(issuer, account, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(totpDigits))
+	values.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// TOTPCode returns the code for the time step containing t.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totpCodeAt(secret, t.Unix()/totpPeriod)
+}
+
+func totpCodeAt(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for range totpDigits {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo), nil
+}
+
+// ValidateTOTP checks the code against the time steps around t and returns
+// the matching step. Steps up to and including lastStep are rejected so a
+// code cannot be replayed.
+func ValidateTOTP(secret, code string, t time.Time, lastStep int64) (int64, error) {
+	code = strings.TrimSpace(code)
+	current := t.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totpCodeAt(secret, step)
+		if err != nil {
+			return 0, err
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, nil
+		}
+	}
+	return 0, ErrTOTPInvalid
+}
+
+// GenerateRecoveryCodes returns n random one-time codes such as
+// "k3vq-7xbd-m2pa".
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for range n {
+		randomData := make([]byte, 8)
+		if _, err := rand.Read(randomData); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(randomData))[:12]
+		codes = append(codes, code[0:4]+"-"+code[4:8]+"-"+code[8:12])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode strips formatting so codes can be typed with or
+// without dashes and in any case.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B, truncated to six digits.
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	cases := []struct {
+		time     int64
+		expected string
+	}{
+		{time: 59, expected: "287082"},
+		{time: 1111111109, expected: "081804"},
+		{time: 1111111111, expected: "050471"},
+		{time: 1234567890, expected: "005924"},
+		{time: 2000000000, expected: "279037"},
+	}
+
+	for _, c := range cases {
+		code, err := TOTPCode(secret, time.Unix(c.time, 0))
+		if err != nil {
+			t.Errorf("Error while generating code: %s", err)
+			continue
+		}
+		if code != c.expected {
+			t.Errorf("Unexpected code at %d:\n\tExpected: %s\n\tGot: %s", c.time, c.expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Error while generating secret: %s", err)
+	}
+	now := time.Now()
+	current := now.Unix() / totpPeriod
+	cases := []struct {
+		codeTime time.Time
+		lastStep int64
+		expected bool
+	}{
+		{codeTime: now, lastStep: 0, expected: true},
+		{codeTime: now.Add(-totpPeriod * time.Second), lastStep: 0, expected: true},
+		{codeTime: now.Add(-5 * totpPeriod * time.Second), lastStep: 0, expected: false},
+		{codeTime: now, lastStep: current, expected: false},
+	}
+
+	for i, c := range cases {
+		code, err := TOTPCode(secret, c.codeTime)
+		if err != nil {
+			t.Errorf("Test nr: %d: Error while generating code: %s", i, err)
+			continue
+		}
+		_, err = ValidateTOTP(secret, code, now, c.lastStep)
+		if c.expected && err != nil {
+			t.Errorf("Test nr: %d: Unexpected error: %s", i, err)
+		}
+		if !c.expected && !errors.Is(err, ErrTOTPInvalid) {
+			t.Errorf("Test nr: %d: Expected %q, got %v", i, ErrTOTPInvalid, err)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("Error while generating recovery codes: %s", err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 14 || strings.Count(code, "-") != 2 {
+			t.Errorf("Unexpected recovery code format: %s", code)
+		}
+		if seen[code] {
+			t.Errorf("Duplicate recovery code: %s", code)
+		}
+		seen[code] = true
+		if NormalizeRecoveryCode(strings.ToUpper(code)) != strings.ReplaceAll(code, "-", "") {
+			t.Errorf("Unexpected normalized code: %s", NormalizeRecoveryCode(code))
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	TokenIssuer   = "chirpy"
+	TokenAudience = "chirpy"
+)
+
+var (
+	ErrTokenMalformed    = errors.New("token is malformed")
+	ErrTokenSignature    = errors.New("token signature is invalid")
+	ErrTokenAlgorithm    = errors.New("token signing method is not allowed")
+	ErrTokenExpired      = errors.New("token has expired")
+	ErrTokenNotValidYet  = errors.New("token is not valid yet")
+	ErrTokenIssuer       = errors.New("token has wrong issuer")
+	ErrTokenAudience     = errors.New("token has wrong audience")
+	ErrTokenMissingClaim = errors.New("token is missing a required claim")
+	ErrTokenRevoked      = errors.New("token has been revoked")
+	ErrTokenClient       = errors.New("token was issued to an OAuth client")
+)
+
+type ValidatorOptions struct {
+	// SigningMethods lists the accepted alg header values.
+	SigningMethods []string
+	Issuer         string
+	Audience       string
+	// Leeway is the allowed clock skew for exp, nbf and iat.
+	Leeway time.Duration
+	// RequiredClaims names registered claims that must be present,
+	// e.g. "sub", "exp", "iat" or "jti".
+	RequiredClaims []string
+}
+
+// DefaultValidatorOptions accepts tokens issued by this server with any
+// algorithm the keyring can verify.
+func DefaultValidatorOptions(keyring *Keyring) ValidatorOptions {
+	return ValidatorOptions{
+		SigningMethods: keyring.Algorithms(),
+		Issuer:         TokenIssuer,
+		Audience:       TokenAudience,
+		Leeway:         30 * time.Second,
+		RequiredClaims: []string{"sub", "exp", "iat"},
+	}
+}
+
+type Validator struct {
+	keyring *Keyring
+	options ValidatorOptions
+}
+
+func NewValidator(keyring *Keyring, options ValidatorOptions) *Validator {
+	return &Validator{
+		keyring: keyring,
+		options: options,
+	}
+}
+
+// Validate verifies the token and returns its claims. Errors wrap one of the
+// ErrToken* values so callers can tell failures apart.
+func (v *Validator) Validate(tokenString string) (*jwt.RegisteredClaims, error) {
+	claims, err := v.ValidateClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return &claims.RegisteredClaims, nil
+}
+
+// ValidateClaims is Validate for access tokens and also returns the role
+// and scope claims.
+func (v *Validator) ValidateClaims(tokenString string) (*Claims, error) {
+	parserOptions := []jwt.ParserOption{
+		jwt.WithLeeway(v.options.Leeway),
+		jwt.WithIssuedAt(),
+	}
+	if len(v.options.Issuer) > 0 {
+		parserOptions = append(parserOptions, jwt.WithIssuer(v.options.Issuer))
+	}
+	if slices.Contains(v.options.RequiredClaims, "exp") {
+		parserOptions = append(parserOptions, jwt.WithExpirationRequired())
+	}
+	claims := Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, v.keyFunc, parserOptions...)
+	if err != nil {
+		return nil, translateJWTError(err)
+	}
+	// Tokens issued before kid headers were added carry no audience. The
+	// keyring only verifies them until the unversioned key's grace period
+	// ends, so they are let through without one until then.
+	_, versioned := token.Header["kid"].(string)
+	if len(v.options.Audience) > 0 && (versioned || len(claims.Audience) > 0) &&
+		!slices.Contains(claims.Audience, v.options.Audience) {
+		return nil, ErrTokenAudience
+	}
+	for _, name := range v.options.RequiredClaims {
+		if !hasRegisteredClaim(&claims.RegisteredClaims, name) {
+			return nil, fmt.Errorf("%w: %s", ErrTokenMissingClaim, name)
+		}
+	}
+	return &claims, nil
+}
+
+// ValidateJWT validates a token from the first-party login and returns the
+// user ID in its subject. Tokens issued to OAuth clients fail with
+// ErrTokenClient, their scopes are only checked by middlewareRequireAuth.
+func (v *Validator) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	claims, err := v.ValidateClaims(tokenString)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(claims.ClientID) > 0 {
+		return uuid.Nil, ErrTokenClient
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: subject is not a user ID", ErrTokenMalformed)
+	}
+	return userID, nil
+}
+
+func (v *Validator) keyFunc(token *jwt.Token) (any, error) {
+	if !slices.Contains(v.options.SigningMethods, token.Method.Alg()) {
+		return nil, ErrTokenAlgorithm
+	}
+	return v.keyring.keyFunc(token)
+}
+
+func translateJWTError(err error) error {
+	switch {
+	case errors.Is(err, ErrTokenAlgorithm):
+		return ErrTokenAlgorithm
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenSignature
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return ErrTokenMissingClaim
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenAudience
+	default:
+		return ErrTokenMalformed
+	}
+}
+
+func hasRegisteredClaim(claims *jwt.RegisteredClaims, name string) bool {
+	switch name {
+	case "iss":
+		return len(claims.Issuer) > 0
+	case "sub":
+		return len(claims.Subject) > 0
+	case "aud":
+		return len(claims.Audience) > 0
+	case "exp":
+		return claims.ExpiresAt != nil
+	case "nbf":
+		return claims.NotBefore != nil
+	case "iat":
+		return claims.IssuedAt != nil
+	case "jti":
+		return len(claims.ID) > 0
+	default:
+		return false
+	}
+}
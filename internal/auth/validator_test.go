@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestValidatorErrors(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("hs", []byte("hello world")))
+	otherKeyring := NewKeyring(NewHMACKey("hs", []byte("other secret")))
+	validClaims := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Audience:  jwt.ClaimStrings{TokenAudience},
+			Subject:   uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}
+	}
+
+	cases := []struct {
+		name     string
+		keyring  *Keyring
+		claims   func() jwt.RegisteredClaims
+		options  func(ValidatorOptions) ValidatorOptions
+		expected error
+	}{
+		{
+			name:     "valid",
+			keyring:  keyring,
+			claims:   validClaims,
+			expected: nil,
+		},
+		{
+			name:    "expired within leeway",
+			keyring: keyring,
+			claims: func() jwt.RegisteredClaims {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+				return claims
+			},
+			expected: nil,
+		},
+		{
+			name:    "expired",
+			keyring: keyring,
+			claims: func() jwt.RegisteredClaims {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return claims
+			},
+			expected: ErrTokenExpired,
+		},
+		{
+			name:     "bad signature",
+			keyring:  otherKeyring,
+			claims:   validClaims,
+			expected: ErrTokenSignature,
+		},
+		{
+			name:    "wrong issuer",
+			keyring: keyring,
+			claims: func() jwt.RegisteredClaims {
+				claims := validClaims()
+				claims.Issuer = "someone else"
+				return claims
+			},
+			expected: ErrTokenIssuer,
+		},
+		{
+			name:    "missing audience",
+			keyring: keyring,
+			claims: func() jwt.RegisteredClaims {
+				claims := validClaims()
+				claims.Audience = nil
+				return claims
+			},
+			expected: ErrTokenAudience,
+		},
+		{
+			name:    "missing subject",
+			keyring: keyring,
+			claims: func() jwt.RegisteredClaims {
+				claims := validClaims()
+				claims.Subject = ""
+				return claims
+			},
+			expected: ErrTokenMissingClaim,
+		},
+		{
+			name:    "algorithm not allowed",
+			keyring: keyring,
+			claims:  validClaims,
+			options: func(options ValidatorOptions) ValidatorOptions {
+				options.SigningMethods = []string{"EdDSA"}
+				return options
+			},
+			expected: ErrTokenAlgorithm,
+		},
+	}
+
+	for _, c := range cases {
+		token, err := c.keyring.Sign(c.claims())
+		if err != nil {
+			t.Errorf("%s: Error while creating JWT: %s", c.name, err)
+			continue
+		}
+		options := DefaultValidatorOptions(keyring)
+		if c.options != nil {
+			options = c.options(options)
+		}
+		_, err = NewValidator(keyring, options).Validate(token)
+		if c.expected == nil && err != nil {
+			t.Errorf("%s: Unexpected error: %s", c.name, err)
+			continue
+		}
+		if c.expected != nil && !errors.Is(err, c.expected) {
+			t.Errorf("%s: Expected error %q, got %v", c.name, c.expected, err)
+			continue
+		}
+	}
+}
+
+func TestValidatorMalformed(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("hs", []byte("hello world")))
+	_, err := NewValidator(keyring, DefaultValidatorOptions(keyring)).Validate("not a token")
+	if !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("Expected error %q, got %v", ErrTokenMalformed, err)
+	}
+}
+
+func TestValidateClaims(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("hs", []byte("hello world")))
+	validator := NewValidator(keyring, DefaultValidatorOptions(keyring))
+	userID := uuid.New()
+
+	cases := []struct {
+		role           string
+		scopes         []string
+		expectedRole   string
+		expectedScopes []string
+	}{
+		{
+			role:           RoleModerator,
+			scopes:         []string{ScopeChirpsRead},
+			expectedRole:   RoleModerator,
+			expectedScopes: []string{ScopeChirpsRead},
+		},
+		{
+			role:           "",
+			scopes:         nil,
+			expectedRole:   RoleUser,
+			expectedScopes: Scopes,
+		},
+	}
+
+	for i, c := range cases {
+		token, err := keyring.MakeAccessToken(userID, c.role, c.scopes, time.Minute)
+		if err != nil {
+			t.Errorf("Test nr: %d: Error while creating JWT: %s", i, err)
+			continue
+		}
+		claims, err := validator.ValidateClaims(token)
+		if err != nil {
+			t.Errorf("Test nr: %d: Unexpected error: %s", i, err)
+			continue
+		}
+		if claims.GetRole() != c.expectedRole {
+			t.Errorf("Test nr: %d: Expected role %q, got %q", i, c.expectedRole, claims.GetRole())
+		}
+		if !slices.Equal(claims.GetScopes(), c.expectedScopes) {
+			t.Errorf("Test nr: %d: Expected scopes %v, got %v", i, c.expectedScopes, claims.GetScopes())
+		}
+	}
+}
+
+func TestValidateJWTClientToken(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("hs", []byte("hello world")))
+	validator := NewValidator(keyring, DefaultValidatorOptions(keyring))
+	userID := uuid.New()
+
+	cases := []struct {
+		clientID string
+		expected error
+	}{
+		{
+			clientID: "",
+			expected: nil,
+		},
+		{
+			clientID: "third-party-app",
+			expected: ErrTokenClient,
+		},
+	}
+
+	for i, c := range cases {
+		claims := NewClaims(userID.String(), time.Minute)
+		claims.ClientID = c.clientID
+		token, err := keyring.Sign(claims)
+		if err != nil {
+			t.Errorf("Test nr: %d: Error while creating JWT: %s", i, err)
+			continue
+		}
+		_, err = validator.ValidateJWT(token)
+		if c.expected == nil && err != nil {
+			t.Errorf("Test nr: %d: Unexpected error: %s", i, err)
+			continue
+		}
+		if c.expected != nil && !errors.Is(err, c.expected) {
+			t.Errorf("Test nr: %d: Expected error %q, got %v", i, c.expected, err)
+		}
+	}
+}
+
+func TestMakeJWTTokenID(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("hs", []byte("hello world")))
+	options := DefaultValidatorOptions(keyring)
+	options.RequiredClaims = append(options.RequiredClaims, "jti")
+	validator := NewValidator(keyring, options)
+	userID := uuid.New()
+
+	tokenIDs := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		token, err := keyring.MakeJWT(userID, time.Minute)
+		if err != nil {
+			t.Fatalf("Error while creating JWT: %s", err)
+		}
+		claims, err := validator.Validate(token)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if tokenIDs[claims.ID] {
+			t.Errorf("Token ID %q was issued twice", claims.ID)
+		}
+		tokenIDs[claims.ID] = true
+	}
+}
+
+func TestValidatorUnversionedAudience(t *testing.T) {
+	secretKey := NewHMACKey("default", []byte("hello world"))
+	claims := jwt.RegisteredClaims{
+		Issuer:    TokenIssuer,
+		Subject:   uuid.New().String(),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+	// Sign without a kid header, the way tokens were issued before keyrings.
+	unversioned := func(claims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("hello world"))
+		if err != nil {
+			t.Fatalf("Error while creating JWT: %s", err)
+		}
+		return token
+	}
+	wrongAudience := claims
+	wrongAudience.Audience = jwt.ClaimStrings{"someone else"}
+
+	cases := []struct {
+		name     string
+		token    string
+		until    time.Time
+		expected error
+	}{
+		{
+			name:     "no audience within grace period",
+			token:    unversioned(claims),
+			until:    time.Now().Add(time.Hour),
+			expected: nil,
+		},
+		{
+			name:     "no audience after grace period",
+			token:    unversioned(claims),
+			until:    time.Now().Add(-time.Hour),
+			expected: ErrTokenSignature,
+		},
+		{
+			name:     "wrong audience within grace period",
+			token:    unversioned(wrongAudience),
+			until:    time.Now().Add(time.Hour),
+			expected: ErrTokenAudience,
+		},
+	}
+
+	for _, c := range cases {
+		keyring := NewKeyring(NewHMACKey("hs", []byte("new secret")))
+		keyring.AllowUnversioned(secretKey, c.until)
+		_, err := NewValidator(keyring, DefaultValidatorOptions(keyring)).Validate(c.token)
+		if c.expected == nil && err != nil {
+			t.Errorf("%s: Unexpected error: %s", c.name, err)
+			continue
+		}
+		if c.expected != nil && !errors.Is(err, c.expected) {
+			t.Errorf("%s: Expected error %q, got %v", c.name, c.expected, err)
+		}
+	}
+}
@@ -7,29 +7,42 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, root_id, quote_of_id)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3,
+	$4,
+	$5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body        string        `json:"body"`
+	UserID      uuid.UUID     `json:"user_id"`
+	InReplyToID uuid.NullUUID `json:"in_reply_to_id"`
+	RootID      uuid.NullUUID `json:"root_id"`
+	QuoteOfID   uuid.NullUUID `json:"quote_of_id"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyToID,
+		arg.RootID,
+		arg.QuoteOfID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,10 +50,47 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.EditedAt,
+		&i.EditCount,
+		&i.InReplyToID,
+		&i.RootID,
+		&i.ReplyCount,
+		&i.LikeCount,
+		&i.QuoteOfID,
+		&i.RechirpCount,
+		&i.QuoteCount,
 	)
 	return i, err
 }
 
+const createChirpRevision = `-- name: CreateChirpRevision :exec
+INSERT INTO chirp_revisions (chirp_id, version, body, created_at, replaced_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	NOW()
+)
+`
+
+type CreateChirpRevisionParams struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Version   int32     `json:"version"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) CreateChirpRevision(ctx context.Context, arg CreateChirpRevisionParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpRevision,
+		arg.ChirpID,
+		arg.Version,
+		arg.Body,
+		arg.CreatedAt,
+	)
+	return err
+}
+
 const deleteChirpById = `-- name: DeleteChirpById :exec
 DELETE FROM chirps
 WHERE id = $1 AND user_id = $2
@@ -56,8 +106,238 @@ func (q *Queries) DeleteChirpById(ctx context.Context, arg DeleteChirpByIdParams
 	return err
 }
 
+const editChirp = `-- name: EditChirp :one
+UPDATE chirps
+SET body = $2,
+edit_count = edit_count + 1,
+edited_at = NOW(),
+updated_at = NOW()
+WHERE id = $1 AND edit_count = $3
+RETURNING id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count
+`
+
+type EditChirpParams struct {
+	ID        uuid.UUID `json:"id"`
+	Body      string    `json:"body"`
+	EditCount int32     `json:"edit_count"`
+}
+
+func (q *Queries) EditChirp(ctx context.Context, arg EditChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, editChirp, arg.ID, arg.Body, arg.EditCount)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.EditCount,
+		&i.InReplyToID,
+		&i.RootID,
+		&i.ReplyCount,
+		&i.LikeCount,
+		&i.QuoteOfID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+	)
+	return i, err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+	SELECT parent.* FROM chirps parent
+	JOIN chirps child ON child.in_reply_to_id = parent.id
+	WHERE child.id = $1
+	UNION ALL
+	SELECT chirps.* FROM chirps
+	JOIN ancestors ON ancestors.in_reply_to_id = chirps.id
+)
+SELECT id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count FROM ancestors
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.EditCount,
+			&i.InReplyToID,
+			&i.RootID,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpReplies = `-- name: GetChirpReplies :many
+SELECT id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count FROM chirps
+WHERE in_reply_to_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT $2 OFFSET $3
+`
+
+type GetChirpRepliesParams struct {
+	InReplyToID uuid.NullUUID `json:"in_reply_to_id"`
+	Limit       int32         `json:"limit"`
+	Offset      int32         `json:"offset"`
+}
+
+func (q *Queries) GetChirpReplies(ctx context.Context, arg GetChirpRepliesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpReplies, arg.InReplyToID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.EditCount,
+			&i.InReplyToID,
+			&i.RootID,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpReplyTree = `-- name: GetChirpReplyTree :many
+WITH RECURSIVE tree AS (
+	SELECT id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count FROM chirps WHERE chirps.in_reply_to_id = $1
+	UNION ALL
+	SELECT chirps.* FROM chirps
+	JOIN tree ON chirps.in_reply_to_id = tree.id
+)
+SELECT id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count FROM tree
+ORDER BY created_at ASC
+LIMIT $2
+`
+
+type GetChirpReplyTreeParams struct {
+	InReplyToID uuid.NullUUID `json:"in_reply_to_id"`
+	Limit       int32         `json:"limit"`
+}
+
+func (q *Queries) GetChirpReplyTree(ctx context.Context, arg GetChirpReplyTreeParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpReplyTree, arg.InReplyToID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.EditCount,
+			&i.InReplyToID,
+			&i.RootID,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpRevisions = `-- name: GetChirpRevisions :many
+SELECT chirp_id, version, body, created_at, replaced_at FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY version ASC
+`
+
+func (q *Queries) GetChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpRevisions, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpRevision
+	for rows.Next() {
+		var i ChirpRevision
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Version,
+			&i.Body,
+			&i.CreatedAt,
+			&i.ReplacedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpsAll = `-- name: GetChirpsAll :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count FROM chirps ORDER BY created_at ASC
 `
 
 func (q *Queries) GetChirpsAll(ctx context.Context) ([]Chirp, error) {
@@ -75,6 +355,15 @@ func (q *Queries) GetChirpsAll(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.EditedAt,
+			&i.EditCount,
+			&i.InReplyToID,
+			&i.RootID,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.QuoteCount,
 		); err != nil {
 			return nil, err
 		}
@@ -90,7 +379,7 @@ func (q *Queries) GetChirpsAll(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsAllByUserID = `-- name: GetChirpsAllByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
 `
 
 func (q *Queries) GetChirpsAllByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -108,6 +397,15 @@ func (q *Queries) GetChirpsAllByUserID(ctx context.Context, userID uuid.UUID) ([
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.EditedAt,
+			&i.EditCount,
+			&i.InReplyToID,
+			&i.RootID,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.QuoteCount,
 		); err != nil {
 			return nil, err
 		}
@@ -123,7 +421,7 @@ func (q *Queries) GetChirpsAllByUserID(ctx context.Context, userID uuid.UUID) ([
 }
 
 const getChirpsOne = `-- name: GetChirpsOne :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, edited_at, edit_count, in_reply_to_id, root_id, reply_count, like_count, quote_of_id, rechirp_count, quote_count FROM chirps WHERE id = $1
 `
 
 func (q *Queries) GetChirpsOne(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -135,6 +433,15 @@ func (q *Queries) GetChirpsOne(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.EditedAt,
+		&i.EditCount,
+		&i.InReplyToID,
+		&i.RootID,
+		&i.ReplyCount,
+		&i.LikeCount,
+		&i.QuoteOfID,
+		&i.RechirpCount,
+		&i.QuoteCount,
 	)
 	return i, err
 }
@@ -11,28 +11,219 @@ import (
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
+	ID           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Body         string        `json:"body"`
+	UserID       uuid.UUID     `json:"user_id"`
+	EditedAt     sql.NullTime  `json:"edited_at"`
+	EditCount    int32         `json:"edit_count"`
+	InReplyToID  uuid.NullUUID `json:"in_reply_to_id"`
+	RootID       uuid.NullUUID `json:"root_id"`
+	ReplyCount   int32         `json:"reply_count"`
+	LikeCount    int32         `json:"like_count"`
+	QuoteOfID    uuid.NullUUID `json:"quote_of_id"`
+	RechirpCount int32         `json:"rechirp_count"`
+	QuoteCount   int32         `json:"quote_count"`
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+type ChirpRevision struct {
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Version    int32     `json:"version"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+type EmailVerificationToken struct {
+	TokenHash string       `json:"token_hash"`
+	CreatedAt time.Time    `json:"created_at"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	Email     string       `json:"email"`
+	UserID    uuid.UUID    `json:"user_id"`
+}
+
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type HandleRedirect struct {
+	OldHandle string    `json:"old_handle"`
 	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
-type RefreshToken struct {
-	Token     string       `json:"token"`
+type LoginAttempt struct {
+	Key           string    `json:"key"`
+	Failures      int32     `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+}
+
+type MfaRecoveryCode struct {
+	CodeHash  string       `json:"code_hash"`
+	CreatedAt time.Time    `json:"created_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	UserID    uuid.UUID    `json:"user_id"`
+}
+
+type MfaTicket struct {
+	TokenHash      string       `json:"token_hash"`
+	CreatedAt      time.Time    `json:"created_at"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	UsedAt         sql.NullTime `json:"used_at"`
+	FailedAttempts int32        `json:"failed_attempts"`
+	UserID         uuid.UUID    `json:"user_id"`
+}
+
+type Mute struct {
+	MuterID   uuid.UUID `json:"muter_id"`
+	MutedID   uuid.UUID `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string        `json:"code_hash"`
+	ClientID      string        `json:"client_id"`
+	UserID        uuid.UUID     `json:"user_id"`
+	RedirectUri   string        `json:"redirect_uri"`
+	Scopes        []string      `json:"scopes"`
+	CodeChallenge string        `json:"code_challenge"`
+	Nonce         string        `json:"nonce"`
+	AuthTime      time.Time     `json:"auth_time"`
+	CreatedAt     time.Time     `json:"created_at"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	UsedAt        sql.NullTime  `json:"used_at"`
+	FamilyID      uuid.NullUUID `json:"family_id"`
+}
+
+type OauthClient struct {
+	ID           string         `json:"id"`
+	SecretHash   sql.NullString `json:"secret_hash"`
+	Name         string         `json:"name"`
+	RedirectUris []string       `json:"redirect_uris"`
+	Scopes       []string       `json:"scopes"`
+	GrantTypes   []string       `json:"grant_types"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+type OidcLoginState struct {
+	StateHash    string       `json:"state_hash"`
+	Provider     string       `json:"provider"`
+	Nonce        string       `json:"nonce"`
+	CodeVerifier string       `json:"code_verifier"`
+	CreatedAt    time.Time    `json:"created_at"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	UsedAt       sql.NullTime `json:"used_at"`
+}
+
+type PasswordResetToken struct {
+	TokenHash string       `json:"token_hash"`
 	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
 	ExpiresAt time.Time    `json:"expires_at"`
-	RevokedAt sql.NullTime `json:"revoked_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
 	UserID    uuid.UUID    `json:"user_id"`
 }
 
+type PersonalAccessToken struct {
+	ID         uuid.UUID    `json:"id"`
+	TokenHash  string       `json:"token_hash"`
+	Name       string       `json:"name"`
+	Scopes     []string     `json:"scopes"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+	UserID     uuid.UUID    `json:"user_id"`
+}
+
+type Rechirp struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RefreshToken struct {
+	TokenHash        string         `json:"token_hash"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	ExpiresAt        time.Time      `json:"expires_at"`
+	RevokedAt        sql.NullTime   `json:"revoked_at"`
+	UserID           uuid.UUID      `json:"user_id"`
+	FamilyID         uuid.UUID      `json:"family_id"`
+	ParentTokenHash  sql.NullString `json:"parent_token_hash"`
+	SessionStartedAt time.Time      `json:"session_started_at"`
+	LastUsedAt       time.Time      `json:"last_used_at"`
+	UserAgent        string         `json:"user_agent"`
+	IpAddress        string         `json:"ip_address"`
+	ClientID         sql.NullString `json:"client_id"`
+	Scopes           []string       `json:"scopes"`
+}
+
+type RevokedAccessToken struct {
+	Jti       string    `json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type TimelineEntry struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ID                  uuid.UUID      `json:"id"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	Email               string         `json:"email"`
+	HashedPassword      string         `json:"hashed_password"`
+	IsChirpyRed         bool           `json:"is_chirpy_red"`
+	EmailVerifiedAt     sql.NullTime   `json:"email_verified_at"`
+	PendingEmail        sql.NullString `json:"pending_email"`
+	TotpSecret          sql.NullString `json:"totp_secret"`
+	TotpEnabledAt       sql.NullTime   `json:"totp_enabled_at"`
+	TotpLastStep        int64          `json:"totp_last_step"`
+	Role                string         `json:"role"`
+	DeletionScheduledAt sql.NullTime   `json:"deletion_scheduled_at"`
+	DisplayName         string         `json:"display_name"`
+	Bio                 string         `json:"bio"`
+	AvatarUrl           string         `json:"avatar_url"`
+	Location            string         `json:"location"`
+	Website             string         `json:"website"`
+	Handle              sql.NullString `json:"handle"`
+	FollowerCount       int32          `json:"follower_count"`
+	FollowingCount      int32          `json:"following_count"`
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID `json:"id"`
+	Provider    string    `json:"provider"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	UserID      uuid.UUID `json:"user_id"`
 }
@@ -0,0 +1,75 @@
+// Package handle validates user handles and finds @mentions in chirps.
+package handle
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	MinLength = 3
+	MaxLength = 20
+)
+
+var (
+	ErrLength   = errors.New("handle must be 3 to 20 characters long")
+	ErrCharset  = errors.New("handle may only contain letters, digits and underscores")
+	ErrNoLetter = errors.New("handle must contain a letter")
+	ErrReserved = errors.New("handle is reserved")
+)
+
+var (
+	handlePattern  = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	letterPattern  = regexp.MustCompile(`[A-Za-z]`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])@([A-Za-z0-9_]+)`)
+)
+
+// reserved handles could be mistaken for the service itself or clash with
+// API paths such as /api/users/me.
+var reserved = []string{
+	"about", "admin", "administrator", "api", "app", "chirpy", "everyone",
+	"help", "here", "login", "logout", "me", "moderator", "null", "oauth",
+	"root", "security", "settings", "staff", "support", "system", "undefined",
+}
+
+// Normalize returns the form handles are compared in. Handles keep the case
+// their owner chose but are unique regardless of case.
+func Normalize(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
+}
+
+// Validate checks a handle without its leading @.
+func Validate(handle string) error {
+	if len(handle) < MinLength || len(handle) > MaxLength {
+		return ErrLength
+	}
+	if !handlePattern.MatchString(handle) {
+		return ErrCharset
+	}
+	if !letterPattern.MatchString(handle) {
+		return ErrNoLetter
+	}
+	if slices.Contains(reserved, Normalize(handle)) {
+		return ErrReserved
+	}
+	return nil
+}
+
+// Mentions returns the normalized handles mentioned in body, each once and
+// in order of appearance. Email addresses such as a@b.com are not mentions.
+func Mentions(body string) []string {
+	mentions := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		mention := match[1]
+		if len(mention) < MinLength || len(mention) > MaxLength {
+			continue
+		}
+		mention = Normalize(mention)
+		if !slices.Contains(mentions, mention) {
+			mentions = append(mentions, mention)
+		}
+	}
+	return mentions
+}
@@ -0,0 +1,55 @@
+package handle
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		handle   string
+		expected error
+	}{
+		{handle: "alice", expected: nil},
+		{handle: "Bob_42", expected: nil},
+		{handle: "_x1", expected: nil},
+		{handle: "ab", expected: ErrLength},
+		{handle: "a_very_long_handle_name", expected: ErrLength},
+		{handle: "ali.ce", expected: ErrCharset},
+		{handle: "žaneta", expected: ErrCharset},
+		{handle: "12345", expected: ErrNoLetter},
+		{handle: "___", expected: ErrNoLetter},
+		{handle: "Admin", expected: ErrReserved},
+		{handle: "me_", expected: nil},
+	}
+
+	for _, c := range cases {
+		err := Validate(c.handle)
+		if !errors.Is(err, c.expected) {
+			t.Errorf("%q: Expected error %v, got %v", c.handle, c.expected, err)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := []struct {
+		body     string
+		expected []string
+	}{
+		{body: "hello @alice and @Bob_42!", expected: []string{"alice", "bob_42"}},
+		{body: "@alice @ALICE @alice", expected: []string{"alice"}},
+		{body: "mail me at bob@example.com", expected: []string{}},
+		{body: "@@alice", expected: []string{}},
+		{body: "(@carol)", expected: []string{"carol"}},
+		{body: "@ab is too short", expected: []string{}},
+		{body: "no mentions here", expected: []string{}},
+	}
+
+	for _, c := range cases {
+		mentions := Mentions(c.body)
+		if !slices.Equal(mentions, c.expected) {
+			t.Errorf("%q: Expected mentions %v, got %v", c.body, c.expected, mentions)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package lockout slows down repeated failed attempts, such as password
+// guesses, with exponential backoff.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// State is the failure history of a single key.
+type State struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Store keeps failure counters. Implementations must update counters
+// atomically so several server instances can share one store.
+type Store interface {
+	Get(ctx context.Context, key string) (State, error)
+	// RecordFailure adds a failure at now. Counters whose last failure is
+	// before resetBefore start again from one.
+	RecordFailure(ctx context.Context, key string, now, resetBefore time.Time) (State, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type Policy struct {
+	// FreeAttempts is the number of failures allowed without any delay.
+	FreeAttempts int
+	// BaseDelay is the delay after the first failure past FreeAttempts. It
+	// doubles with each further failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// ResetAfter forgets failures once no new one happened for this long.
+	ResetAfter time.Duration
+}
+
+// Delay returns how long after the last failure the next attempt is allowed.
+func (p Policy) Delay(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < over && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func NewLimiter(store Store, policy Policy) *Limiter {
+	return &Limiter{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// Check returns how long the caller has to wait before the next attempt for
+// any of the keys is allowed. Zero means the attempt may proceed.
+func (l *Limiter) Check(ctx context.Context, keys ...string) (time.Duration, error) {
+	now := l.now()
+	var retryAfter time.Duration
+	for _, key := range keys {
+		state, err := l.store.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		retryAfter = max(retryAfter, l.retryAfter(state, now))
+	}
+	return retryAfter, nil
+}
+
+// Fail records a failed attempt for every key and returns the resulting
+// wait before the next attempt.
+func (l *Limiter) Fail(ctx context.Context, keys ...string) (time.Duration, error) {
+	now := l.now()
+	var retryAfter time.Duration
+	for _, key := range keys {
+		state, err := l.store.RecordFailure(ctx, key, now, now.Add(-l.policy.ResetAfter))
+		if err != nil {
+			return 0, err
+		}
+		retryAfter = max(retryAfter, l.retryAfter(state, now))
+	}
+	return retryAfter, nil
+}
+
+// Succeed clears the counters of every key.
+func (l *Limiter) Succeed(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := l.store.Reset(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) retryAfter(state State, now time.Time) time.Duration {
+	if state.Failures == 0 || now.Sub(state.LastFailure) >= l.policy.ResetAfter {
+		return 0
+	}
+	return max(state.LastFailure.Add(l.policy.Delay(state.Failures)).Sub(now), 0)
+}
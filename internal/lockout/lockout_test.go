@@ -0,0 +1,85 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     10 * time.Second,
+		ResetAfter:   time.Hour,
+	}
+	cases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: 0},
+		{failures: 3, expected: 0},
+		{failures: 4, expected: time.Second},
+		{failures: 5, expected: 2 * time.Second},
+		{failures: 7, expected: 8 * time.Second},
+		{failures: 8, expected: 10 * time.Second},
+		{failures: 100, expected: 10 * time.Second},
+	}
+
+	for _, c := range cases {
+		delay := policy.Delay(c.failures)
+		if delay != c.expected {
+			t.Errorf("Unexpected delay after %d failures:\n\tExpected: %s\n\tGot: %s", c.failures, c.expected, delay)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(NewMemoryStore(), Policy{
+		FreeAttempts: 2,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		ResetAfter:   24 * time.Hour,
+	})
+	limiter.now = func() time.Time { return now }
+
+	for i := range 2 {
+		retryAfter, err := limiter.Fail(ctx, "account:a", "ip:1")
+		if err != nil || retryAfter != 0 {
+			t.Fatalf("Failure nr: %d: expected no delay, got %s (%v)", i, retryAfter, err)
+		}
+	}
+	retryAfter, err := limiter.Fail(ctx, "account:a", "ip:1")
+	if err != nil || retryAfter != time.Minute {
+		t.Fatalf("Expected a minute of delay, got %s (%v)", retryAfter, err)
+	}
+
+	now = now.Add(30 * time.Second)
+	retryAfter, err = limiter.Check(ctx, "account:a")
+	if err != nil || retryAfter != 30*time.Second {
+		t.Errorf("Expected 30s of delay, got %s (%v)", retryAfter, err)
+	}
+	retryAfter, err = limiter.Check(ctx, "account:b", "ip:1")
+	if err != nil || retryAfter != 30*time.Second {
+		t.Errorf("Expected the IP key to be delayed by 30s, got %s (%v)", retryAfter, err)
+	}
+
+	if err := limiter.Succeed(ctx, "account:a", "ip:1"); err != nil {
+		t.Fatalf("Error while resetting counters: %s", err)
+	}
+	retryAfter, err = limiter.Check(ctx, "account:a", "ip:1")
+	if err != nil || retryAfter != 0 {
+		t.Errorf("Expected no delay after success, got %s (%v)", retryAfter, err)
+	}
+
+	for range 3 {
+		limiter.Fail(ctx, "account:c")
+	}
+	now = now.Add(25 * time.Hour)
+	retryAfter, err = limiter.Fail(ctx, "account:c")
+	if err != nil || retryAfter != 0 {
+		t.Errorf("Expected old failures to be forgotten, got %s (%v)", retryAfter, err)
+	}
+}
@@ -0,0 +1,57 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryStorePruneSize is the number of keys above which stale entries are
+// dropped.
+const memoryStorePruneSize = 10000
+
+// MemoryStore keeps counters in process memory. It is only suitable for a
+// single server instance.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		states: map[string]State{},
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[key], nil
+}
+
+func (s *MemoryStore) RecordFailure(ctx context.Context, key string, now, resetBefore time.Time) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.states) > memoryStorePruneSize {
+		for k, state := range s.states {
+			if state.LastFailure.Before(resetBefore) {
+				delete(s.states, k)
+			}
+		}
+	}
+	state := s.states[key]
+	if state.LastFailure.Before(resetBefore) {
+		state.Failures = 0
+	}
+	state.Failures++
+	state.LastFailure = now
+	s.states[key] = state
+	return state, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
@@ -0,0 +1,55 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/hrncacz/go-chirpy/internal/database"
+)
+
+// PostgresStore keeps counters in the login_attempts table so they are
+// shared by all server instances.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+	}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) (State, error) {
+	attempts, err := s.db.GetLoginAttempts(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	return State{
+		Failures:    int(attempts.Failures),
+		LastFailure: attempts.LastFailureAt,
+	}, nil
+}
+
+func (s *PostgresStore) RecordFailure(ctx context.Context, key string, now, resetBefore time.Time) (State, error) {
+	attempts, err := s.db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key:         key,
+		Now:         now,
+		ResetBefore: resetBefore,
+	})
+	if err != nil {
+		return State{}, err
+	}
+	return State{
+		Failures:    int(attempts.Failures),
+		LastFailure: attempts.LastFailureAt,
+	}, nil
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.db.ResetLoginAttempts(ctx, key)
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers plain text emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// format renders the message as an RFC 5322 email.
+func format(from string, msg Message) []byte {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&builder, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&builder, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&builder, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
+
+// headerValue strips line breaks so values cannot inject extra headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	cases := []Message{
+		{
+			To:      "first@example.com",
+			Subject: "Hello",
+			Body:    "hello world",
+		},
+		{
+			To:      "second@example.com",
+			Subject: "Reset your password",
+			Body:    "token",
+		},
+	}
+
+	m := &MemoryMailer{}
+	for _, c := range cases {
+		if err := m.Send(context.Background(), c); err != nil {
+			t.Errorf("Error while sending message: %s", err)
+		}
+	}
+	messages := m.Messages()
+	if len(messages) != len(cases) {
+		t.Fatalf("Expected %d messages, got %d", len(cases), len(messages))
+	}
+	for i, c := range cases {
+		if messages[i] != c {
+			t.Errorf("Message nr: %d differs:\n\tSent: %+v\n\tStored: %+v", i, c, messages[i])
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{
+		Dir:  dir,
+		From: "chirpy@example.com",
+	}
+	err := m.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Reset your password",
+		Body:    "line one\nline two",
+	})
+	if err != nil {
+		t.Fatalf("Error while sending message: %s", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one written message, got %d (%v)", len(entries), err)
+	}
+	data, err := os.ReadFile(dir + "/" + entries[0].Name())
+	if err != nil {
+		t.Fatalf("Error while reading message: %s", err)
+	}
+	for _, expected := range []string{
+		"From: chirpy@example.com\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Reset your password\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Message does not contain %q:\n%s", expected, data)
+		}
+	}
+}
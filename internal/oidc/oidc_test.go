@@ -0,0 +1,192 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hrncacz/go-chirpy/internal/auth"
+)
+
+// mockIdP is a minimal OpenID provider that issues ID tokens for a single
+// authorization code.
+type mockIdP struct {
+	server  *httptest.Server
+	keyring *auth.Keyring
+	claims  jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error while generating RSA key: %s", err)
+	}
+	idp := &mockIdP{keyring: auth.NewKeyring(auth.NewRSAKey("idp-key", privateKey))}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(idp.keyring.JWKS())
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		if clientID != "chirpy" || clientSecret != "secret" {
+			w.WriteHeader(401)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.PostFormValue("code") != "good-code" || !auth.VerifyPKCE(r.PostFormValue("code_verifier"), testCodeChallenge) {
+			w.WriteHeader(400)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		idToken, err := idp.keyring.Sign(idp.claims)
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            "employee-42",
+		"aud":            "chirpy",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"nonce":          "nonce",
+		"email":          "alice@corp.example",
+		"email_verified": true,
+	}
+}
+
+// Example from RFC 7636 appendix B.
+const (
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestProviderLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	provider, err := Discover(context.Background(), idp.server.Client(), Config{
+		Name:         "corp",
+		Issuer:       idp.server.URL,
+		ClientID:     "chirpy",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/api/login/oidc/corp/callback",
+	})
+	if err != nil {
+		t.Fatalf("Error while discovering provider: %s", err)
+	}
+
+	authCodeURL, err := url.Parse(provider.AuthCodeURL("state", "nonce", testCodeChallenge))
+	if err != nil {
+		t.Fatalf("Error while parsing auth code URL: %s", err)
+	}
+	if authCodeURL.Query().Get("code_challenge") != testCodeChallenge || authCodeURL.Query().Get("scope") != "openid" {
+		t.Errorf("Unexpected auth code URL: %s", authCodeURL)
+	}
+
+	cases := []struct {
+		name     string
+		claims   func() jwt.MapClaims
+		expected error
+	}{
+		{
+			name:     "valid",
+			claims:   idp.validClaims,
+			expected: nil,
+		},
+		{
+			name: "wrong nonce",
+			claims: func() jwt.MapClaims {
+				claims := idp.validClaims()
+				claims["nonce"] = "other"
+				return claims
+			},
+			expected: ErrNonceMismatch,
+		},
+		{
+			name: "wrong audience",
+			claims: func() jwt.MapClaims {
+				claims := idp.validClaims()
+				claims["aud"] = "someone-else"
+				return claims
+			},
+			expected: ErrInvalidIDToken,
+		},
+		{
+			name: "wrong issuer",
+			claims: func() jwt.MapClaims {
+				claims := idp.validClaims()
+				claims["iss"] = "https://evil.example"
+				return claims
+			},
+			expected: ErrInvalidIDToken,
+		},
+		{
+			name: "expired",
+			claims: func() jwt.MapClaims {
+				claims := idp.validClaims()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return claims
+			},
+			expected: ErrInvalidIDToken,
+		},
+	}
+
+	for _, c := range cases {
+		idp.claims = c.claims()
+		rawIDToken, err := provider.Exchange(context.Background(), "good-code", testCodeVerifier)
+		if err != nil {
+			t.Errorf("%s: Error while exchanging code: %s", c.name, err)
+			continue
+		}
+		idToken, err := provider.VerifyIDToken(context.Background(), rawIDToken, "nonce")
+		if c.expected == nil && err != nil {
+			t.Errorf("%s: Unexpected error: %s", c.name, err)
+			continue
+		}
+		if c.expected != nil && !errors.Is(err, c.expected) {
+			t.Errorf("%s: Expected error %q, got %v", c.name, c.expected, err)
+			continue
+		}
+		if c.expected == nil && (idToken.Subject != "employee-42" || idToken.Email != "alice@corp.example" || !idToken.EmailVerified) {
+			t.Errorf("%s: Unexpected ID token: %+v", c.name, idToken)
+		}
+	}
+
+	if _, err := provider.Exchange(context.Background(), "good-code", "wrong-verifier-wrong-verifier-wrong-verifier"); err == nil {
+		t.Errorf("Expected exchange with a wrong code verifier to fail")
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	_, err := Discover(context.Background(), idp.server.Client(), Config{
+		Issuer:   idp.server.URL + "/",
+		ClientID: "chirpy",
+	})
+	if !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("Expected error %q, got %v", ErrIssuerMismatch, err)
+	}
+}
@@ -0,0 +1,205 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("ID token is invalid")
+	ErrNonceMismatch  = errors.New("ID token nonce does not match")
+)
+
+// keysMinRefresh limits how often an unknown kid makes us refetch the JWKS.
+const keysMinRefresh = time.Minute
+
+// IDToken holds the claims of a verified ID token that we use.
+type IDToken struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	Email           string `json:"email"`
+	EmailVerified   any    `json:"email_verified"`
+}
+
+// VerifyIDToken checks the signature against the provider's JWKS and the
+// iss, aud, azp, exp, iat and nonce claims as OpenID Connect Core 3.1.3.7
+// requires.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	claims := idTokenClaims{}
+	_, err := jwt.ParseWithClaims(
+		rawIDToken,
+		&claims,
+		func(token *jwt.Token) (any, error) { return p.key(ctx, token) },
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	if len(claims.Subject) == 0 {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: azp is not our client", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+	idToken := &IDToken{
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	}
+	// Some providers send email_verified as a string.
+	switch verified := claims.EmailVerified.(type) {
+	case bool:
+		idToken.EmailVerified = verified
+	case string:
+		idToken.EmailVerified = verified == "true"
+	}
+	return idToken, nil
+}
+
+// key finds the verification key for the token's kid, refetching the JWKS
+// when the provider may have rotated keys.
+func (p *Provider) key(ctx context.Context, token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysLoadedAt) < keysMinRefresh {
+		return nil, errors.New("unknown signing key")
+	}
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysLoadedAt = time.Now()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// lookupKey accepts a missing kid only when the provider has a single key.
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if len(kid) == 0 && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context) (map[string]any, error) {
+	type jwk struct {
+		KeyType string `json:"kty"`
+		KeyID   string `json:"kid"`
+		Use     string `json:"use"`
+		N       string `json:"n"`
+		E       string `json:"e"`
+		Curve   string `json:"crv"`
+		X       string `json:"x"`
+		Y       string `json:"y"`
+	}
+	type jwkSet struct {
+		Keys []jwk `json:"keys"`
+	}
+	set := jwkSet{}
+	if err := getJSON(ctx, p.client, p.jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if len(k.Use) > 0 && k.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(k.KeyType, k.Curve, k.N, k.E, k.X, k.Y)
+		if err != nil {
+			// Keys of unsupported types are skipped so one odd key does not
+			// break sign-in.
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	return keys, nil
+}
+
+func parseJWK(keyType, curve, n, e, x, y string) (any, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch keyType {
+	case "RSA":
+		modulus, err := decode(n)
+		if err != nil {
+			return nil, err
+		}
+		exponent, err := decode(e)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}, nil
+	case "EC":
+		if curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", curve)
+		}
+		xBytes, err := decode(x)
+		if err != nil {
+			return nil, err
+		}
+		yBytes, err := decode(y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(xBytes),
+			Y:     new(big.Int).SetBytes(yBytes),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return key, nil
+	case "OKP":
+		if curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", curve)
+		}
+		xBytes, err := decode(x)
+		if err != nil {
+			return nil, err
+		}
+		if len(xBytes) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(xBytes), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+}
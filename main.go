@@ -1,26 +1,69 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/hrncacz/go-chirpy/internal/auth"
 	"github.com/hrncacz/go-chirpy/internal/database"
+	"github.com/hrncacz/go-chirpy/internal/lockout"
+	"github.com/hrncacz/go-chirpy/internal/mailer"
+	"github.com/hrncacz/go-chirpy/internal/oidc"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	fileServerHits atomic.Int32
-	db             *database.Queries
-	dev            bool
-	jwtSignString  string
-	jwtExpiration  time.Duration
-	polkaAPIKey    string
+	fileServerHits    atomic.Int32
+	db                *database.Queries
+	sqlDB             *sql.DB
+	dev               bool
+	jwtKeys           *auth.Keyring
+	jwtValidator      *auth.Validator
+	jwtExpiration     time.Duration
+	passwords         *auth.PasswordHashing
+	passwordPolicy    auth.PasswordPolicy
+	refreshExpiration time.Duration
+	tokenHashSecret   string
+	polkaAPIKey       string
+	baseURL           string
+	mailer            mailer.Mailer
+	oidcProviders     map[string]*oidc.Provider
+
+	loginAccountLimiter *lockout.Limiter
+	loginIPLimiter      *lockout.Limiter
+
+	passwordResetExpiration     time.Duration
+	emailVerificationExpiration time.Duration
+	emailVerificationGrace      time.Duration
+	mfaTicketExpiration         time.Duration
+	accountDeletionGrace        time.Duration
+	handleRedirectPeriod        time.Duration
+	chirpEdits                  chirpEditPolicy
+	chirpRedEdits               chirpEditPolicy
+}
+
+// inTx runs fn with queries bound to one transaction and commits it when fn
+// returns nil.
+func (cfg *apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(cfg.db.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -57,6 +100,176 @@ func (cfg *apiConfig) middlewareMeticsReset(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+// loadJWTKeys builds the signing keyring. Without a keyring file the
+// JWT_SECRET is used as the only HS256 key. With one, JWT_SECRET is kept only
+// to verify tokens issued before the switch until they expire.
+func loadJWTKeys(keyringFile, jwtSecret string, jwtExpiration time.Duration) (*auth.Keyring, error) {
+	secretKey := auth.NewHMACKey("default", []byte(jwtSecret))
+	if len(keyringFile) == 0 {
+		if len(jwtSecret) == 0 {
+			return nil, errors.New("JWT_SECRET or JWT_KEYRING_FILE must be set")
+		}
+		keyring := auth.NewKeyring(secretKey)
+		keyring.AllowUnversioned(secretKey, time.Now().Add(jwtExpiration))
+		return keyring, nil
+	}
+	keyring, err := auth.LoadKeyringFile(keyringFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(jwtSecret) > 0 {
+		keyring.AllowUnversioned(secretKey, time.Now().Add(jwtExpiration))
+	}
+	return keyring, nil
+}
+
+// loadMailer configures outgoing email from MAIL_BACKEND, which is either
+// "smtp" or "file" (the default, writing to MAIL_DIR).
+func loadMailer() (mailer.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if len(from) == 0 {
+		from = "chirpy@localhost"
+	}
+	switch os.Getenv("MAIL_BACKEND") {
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		return &mailer.SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	case "", "file":
+		dir := os.Getenv("MAIL_DIR")
+		if len(dir) == 0 {
+			dir = "./mail"
+		}
+		return &mailer.FileMailer{
+			Dir:  dir,
+			From: from,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_BACKEND %q", os.Getenv("MAIL_BACKEND"))
+	}
+}
+
+// loadPasswordHashing reads the argon2id parameters for new hashes from
+// ARGON2_MEMORY (KiB), ARGON2_ITERATIONS and ARGON2_PARALLELISM. Existing
+// bcrypt hashes stay valid and are upgraded on the next login.
+func loadPasswordHashing() (*auth.PasswordHashing, error) {
+	params := auth.DefaultArgon2idParams
+	if memory := os.Getenv("ARGON2_MEMORY"); len(memory) > 0 {
+		value, err := strconv.ParseUint(memory, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARGON2_MEMORY: %w", err)
+		}
+		params.Memory = uint32(value)
+	}
+	if iterations := os.Getenv("ARGON2_ITERATIONS"); len(iterations) > 0 {
+		value, err := strconv.ParseUint(iterations, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARGON2_ITERATIONS: %w", err)
+		}
+		params.Iterations = uint32(value)
+	}
+	if parallelism := os.Getenv("ARGON2_PARALLELISM"); len(parallelism) > 0 {
+		value, err := strconv.ParseUint(parallelism, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARGON2_PARALLELISM: %w", err)
+		}
+		params.Parallelism = uint8(value)
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return auth.NewPasswordHashing(auth.NewArgon2idHasher(params), auth.NewBcryptHasher(12)), nil
+}
+
+// loadPasswordPolicy applies PASSWORD_MIN_LENGTH and PASSWORD_MAX_LENGTH to
+// the default policy. BREACHED_PASSWORDS_FILE replaces the built-in list of
+// leaked passwords with a larger one.
+func loadPasswordPolicy() (auth.PasswordPolicy, error) {
+	policy := auth.DefaultPasswordPolicy()
+	if minLength := os.Getenv("PASSWORD_MIN_LENGTH"); len(minLength) > 0 {
+		value, err := strconv.Atoi(minLength)
+		if err != nil {
+			return policy, fmt.Errorf("invalid PASSWORD_MIN_LENGTH: %w", err)
+		}
+		policy.MinLength = value
+	}
+	if maxLength := os.Getenv("PASSWORD_MAX_LENGTH"); len(maxLength) > 0 {
+		value, err := strconv.Atoi(maxLength)
+		if err != nil {
+			return policy, fmt.Errorf("invalid PASSWORD_MAX_LENGTH: %w", err)
+		}
+		policy.MaxLength = value
+	}
+	if breachedFile := os.Getenv("BREACHED_PASSWORDS_FILE"); len(breachedFile) > 0 {
+		file, err := os.Open(breachedFile)
+		if err != nil {
+			return policy, err
+		}
+		defer file.Close()
+		policy.Breached, err = auth.ReadBreachedPasswords(file)
+		if err != nil {
+			return policy, err
+		}
+	}
+	return policy, nil
+}
+
+// loadLoginAttemptStore picks where failed logins are counted from
+// LOGIN_ATTEMPTS_BACKEND: "memory" (the default) for a single instance or
+// "postgres" to share counters between instances.
+func loadLoginAttemptStore(db *database.Queries) (lockout.Store, error) {
+	switch os.Getenv("LOGIN_ATTEMPTS_BACKEND") {
+	case "", "memory":
+		return lockout.NewMemoryStore(), nil
+	case "postgres":
+		return lockout.NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown LOGIN_ATTEMPTS_BACKEND %q", os.Getenv("LOGIN_ATTEMPTS_BACKEND"))
+	}
+}
+
+// loadOIDCProviders enables sign-in through an external identity provider
+// when OIDC_ISSUER is set. The provider is served under
+// /api/login/oidc/{OIDC_PROVIDER_NAME}.
+func loadOIDCProviders(baseURL string) (map[string]*oidc.Provider, error) {
+	providers := map[string]*oidc.Provider{}
+	issuer := os.Getenv("OIDC_ISSUER")
+	if len(issuer) == 0 {
+		return providers, nil
+	}
+	name := os.Getenv("OIDC_PROVIDER_NAME")
+	if len(name) == 0 {
+		name = "oidc"
+	}
+	scopes := os.Getenv("OIDC_SCOPES")
+	if len(scopes) == 0 {
+		scopes = "email"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	provider, err := oidc.Discover(ctx, nil, oidc.Config{
+		Name:         name,
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  baseURL + "/api/login/oidc/" + name + "/callback",
+		Scopes:       strings.Fields(scopes),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("OIDC provider %q: %w", name, err)
+	}
+	providers[name] = provider
+	return providers, nil
+}
+
 func main() {
 	err := godotenv.Load("./.env")
 	if err != nil {
@@ -64,6 +277,10 @@ func main() {
 	}
 	dbURL := os.Getenv("DB_URL")
 	jwtSecret := os.Getenv("JWT_SECRET")
+	tokenHashSecret := os.Getenv("TOKEN_HASH_SECRET")
+	if len(tokenHashSecret) == 0 {
+		log.Fatalln("TOKEN_HASH_SECRET must be set")
+	}
 	polkaAPIKey := os.Getenv("POLKA_API")
 	dev := os.Getenv("PLATFORM")
 	db, err := sql.Open("postgres", dbURL)
@@ -71,17 +288,122 @@ func main() {
 		log.Fatal(err)
 	}
 	dbQueries := database.New(db)
+	jwtExpiration := 1 * time.Hour
+	jwtKeys, err := loadJWTKeys(os.Getenv("JWT_KEYRING_FILE"), jwtSecret, jwtExpiration)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwtValidatorOptions := auth.DefaultValidatorOptions(jwtKeys)
+	if jwtLeeway := os.Getenv("JWT_LEEWAY"); len(jwtLeeway) > 0 {
+		jwtValidatorOptions.Leeway, err = time.ParseDuration(jwtLeeway)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	apiCfg := &apiConfig{
-		fileServerHits: atomic.Int32{},
-		db:             dbQueries,
-		dev:            false,
-		jwtSignString:  jwtSecret,
-		jwtExpiration:  1 * time.Hour,
-		polkaAPIKey:    polkaAPIKey,
+		fileServerHits:    atomic.Int32{},
+		db:                dbQueries,
+		sqlDB:             db,
+		dev:               false,
+		jwtKeys:           jwtKeys,
+		jwtValidator:      auth.NewValidator(jwtKeys, jwtValidatorOptions),
+		jwtExpiration:     jwtExpiration,
+		refreshExpiration: 60 * 24 * time.Hour,
+		tokenHashSecret:   tokenHashSecret,
+		polkaAPIKey:       polkaAPIKey,
 	}
 	if dev == "dev" {
 		apiCfg.dev = true
 	}
+	apiCfg.baseURL = os.Getenv("BASE_URL")
+	if len(apiCfg.baseURL) == 0 {
+		apiCfg.baseURL = "http://localhost:8080"
+	}
+	apiCfg.mailer, err = loadMailer()
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.oidcProviders, err = loadOIDCProviders(apiCfg.baseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.passwords, err = loadPasswordHashing()
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.passwordPolicy, err = loadPasswordPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+	loginAttemptStore, err := loadLoginAttemptStore(dbQueries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.loginAccountLimiter = lockout.NewLimiter(loginAttemptStore, lockout.Policy{
+		FreeAttempts: 5,
+		BaseDelay:    1 * time.Second,
+		MaxDelay:     15 * time.Minute,
+		ResetAfter:   24 * time.Hour,
+	})
+	apiCfg.loginIPLimiter = lockout.NewLimiter(loginAttemptStore, lockout.Policy{
+		FreeAttempts: 20,
+		BaseDelay:    1 * time.Second,
+		MaxDelay:     15 * time.Minute,
+		ResetAfter:   1 * time.Hour,
+	})
+	apiCfg.passwordResetExpiration = 1 * time.Hour
+	apiCfg.emailVerificationExpiration = 48 * time.Hour
+	apiCfg.emailVerificationGrace = 24 * time.Hour
+	apiCfg.mfaTicketExpiration = 5 * time.Minute
+	if grace := os.Getenv("EMAIL_VERIFICATION_GRACE"); len(grace) > 0 {
+		apiCfg.emailVerificationGrace, err = time.ParseDuration(grace)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	apiCfg.accountDeletionGrace = 30 * 24 * time.Hour
+	if grace := os.Getenv("ACCOUNT_DELETION_GRACE"); len(grace) > 0 {
+		apiCfg.accountDeletionGrace, err = time.ParseDuration(grace)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	apiCfg.handleRedirectPeriod = 30 * 24 * time.Hour
+	if period := os.Getenv("HANDLE_REDIRECT_PERIOD"); len(period) > 0 {
+		apiCfg.handleRedirectPeriod, err = time.ParseDuration(period)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	apiCfg.chirpEdits = chirpEditPolicy{Window: 5 * time.Minute, MaxEdits: 1}
+	apiCfg.chirpRedEdits = chirpEditPolicy{Window: 1 * time.Hour, MaxEdits: 5}
+	if window := os.Getenv("CHIRP_EDIT_WINDOW"); len(window) > 0 {
+		apiCfg.chirpEdits.Window, err = time.ParseDuration(window)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if window := os.Getenv("CHIRP_RED_EDIT_WINDOW"); len(window) > 0 {
+		apiCfg.chirpRedEdits.Window, err = time.ParseDuration(window)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if maxEdits := os.Getenv("CHIRP_EDIT_MAX"); len(maxEdits) > 0 {
+		value, err := strconv.ParseInt(maxEdits, 10, 32)
+		if err != nil {
+			log.Fatal(err)
+		}
+		apiCfg.chirpEdits.MaxEdits = int32(value)
+	}
+	if maxEdits := os.Getenv("CHIRP_RED_EDIT_MAX"); len(maxEdits) > 0 {
+		value, err := strconv.ParseInt(maxEdits, 10, 32)
+		if err != nil {
+			log.Fatal(err)
+		}
+		apiCfg.chirpRedEdits.MaxEdits = int32(value)
+	}
+	go purgeDeletedAccounts(context.Background(), apiCfg, 1*time.Hour)
 	mux := http.NewServeMux()
 	httpServer := &http.Server{}
 
@@ -92,6 +414,13 @@ func main() {
 	//ADMIN
 	mux.HandleFunc("GET /admin/metrics", apiCfg.middlewareMeticsLog)
 	mux.HandleFunc("POST /admin/reset", apiCfg.middlewareMeticsReset)
+	//WELL-KNOWN
+	mux.HandleFunc("GET /.well-known/jwks.json", getJWKS(apiCfg))
+	mux.HandleFunc("GET /.well-known/openid-configuration", getOpenIDConfiguration(apiCfg))
+	//OAUTH
+	mux.HandleFunc("GET /oauth/authorize", authorize(apiCfg))
+	mux.HandleFunc("POST /oauth/authorize", approveAuthorization(apiCfg))
+	mux.HandleFunc("POST /oauth/token", oauthToken(apiCfg))
 	//API
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "text/plain; charset=utf-8")
@@ -99,14 +428,65 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 	mux.HandleFunc("POST /api/users", createUser(apiCfg))
-	mux.HandleFunc("GET /api/chirps", getChirpsAll(apiCfg))
-	mux.HandleFunc("GET /api/chirps/{chirpID}", getChirpsOne(apiCfg))
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", deleteChirp(apiCfg))
-	mux.HandleFunc("POST /api/chirps", createChirp(apiCfg))
+	mux.HandleFunc("GET /api/chirps", apiCfg.middlewareOptionalAuth(getChirpsAll(apiCfg)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.middlewareOptionalAuth(getChirpsOne(apiCfg)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/history", apiCfg.middlewareOptionalAuth(getChirpHistory(apiCfg)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/replies", apiCfg.middlewareOptionalAuth(getChirpReplies(apiCfg)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.middlewareOptionalAuth(getChirpThread(apiCfg)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/likes", apiCfg.middlewareOptionalAuth(getChirpLikes(apiCfg)))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.middlewareRequireAuth(likeChirp(apiCfg), auth.ScopeChirpsWrite))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.middlewareRequireAuth(unlikeChirp(apiCfg), auth.ScopeChirpsWrite))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/quotes", apiCfg.middlewareOptionalAuth(getChirpQuotes(apiCfg)))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.middlewareRequireAuth(rechirp(apiCfg), auth.ScopeChirpsWrite))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.middlewareRequireAuth(undoRechirp(apiCfg), auth.ScopeChirpsWrite))
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.middlewareRequireAuth(editChirp(apiCfg), auth.ScopeChirpsWrite))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.middlewareRequireAuth(deleteChirp(apiCfg), auth.ScopeChirpsWrite))
+	mux.HandleFunc("POST /api/chirps", apiCfg.middlewareRequireAuth(createChirp(apiCfg), auth.ScopeChirpsWrite))
 	mux.HandleFunc("POST /api/login", login(apiCfg))
+	mux.HandleFunc("POST /api/login/mfa", loginMFA(apiCfg))
+	mux.HandleFunc("GET /api/login/oidc/{provider}", startOIDCLogin(apiCfg))
+	mux.HandleFunc("GET /api/login/oidc/{provider}/callback", finishOIDCLogin(apiCfg))
+	mux.HandleFunc("POST /api/mfa/totp/enroll", enrollTOTP(apiCfg))
+	mux.HandleFunc("POST /api/mfa/totp/confirm", confirmTOTP(apiCfg))
+	mux.HandleFunc("DELETE /api/mfa/totp", disableTOTP(apiCfg))
 	mux.HandleFunc("POST /api/refresh", refresh(apiCfg))
 	mux.HandleFunc("POST /api/revoke", revoke(apiCfg))
-	mux.HandleFunc("PUT /api/users", changeEmailPassword(apiCfg))
+	mux.HandleFunc("POST /api/introspect", introspect(apiCfg))
+	mux.HandleFunc("GET /api/sessions", getSessions(apiCfg))
+	mux.HandleFunc("DELETE /api/sessions/{id}", deleteSession(apiCfg))
+	mux.HandleFunc("POST /api/logout-all", logoutAll(apiCfg))
+	mux.HandleFunc("POST /api/tokens", createPersonalAccessToken(apiCfg))
+	mux.HandleFunc("GET /api/tokens", getPersonalAccessTokens(apiCfg))
+	mux.HandleFunc("DELETE /api/tokens/{id}", deletePersonalAccessToken(apiCfg))
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.middlewareRequireSession(createOAuthClient(apiCfg)))
+	mux.HandleFunc("GET /api/oauth/clients", apiCfg.middlewareRequireAuth(getOAuthClients(apiCfg)))
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientID}", apiCfg.middlewareRequireSession(deleteOAuthClient(apiCfg)))
+	mux.HandleFunc("GET /api/users/me", apiCfg.middlewareRequireAuth(getMe(apiCfg)))
+	mux.HandleFunc("PATCH /api/users/me", apiCfg.middlewareRequireAuth(updateMe(apiCfg), auth.ScopeProfileWrite))
+	mux.HandleFunc("GET /api/users/{id}", getUserProfile(apiCfg))
+	mux.HandleFunc("GET /api/users/{id}/{list}", apiCfg.middlewareOptionalAuth(getUserList(map[string]http.HandlerFunc{
+		"likes":     getUserLikes(apiCfg),
+		"followers": getFollowers(apiCfg),
+		"following": getFollowing(apiCfg),
+	})))
+	mux.HandleFunc("POST /api/users/{id}/follow", apiCfg.middlewareRequireAuth(followUser(apiCfg), auth.ScopeProfileWrite))
+	mux.HandleFunc("DELETE /api/users/{id}/follow", apiCfg.middlewareRequireAuth(unfollowUser(apiCfg), auth.ScopeProfileWrite))
+	mux.HandleFunc("POST /api/users/{id}/block", apiCfg.middlewareRequireAuth(blockUser(apiCfg), auth.ScopeProfileWrite))
+	mux.HandleFunc("DELETE /api/users/{id}/block", apiCfg.middlewareRequireAuth(unblockUser(apiCfg), auth.ScopeProfileWrite))
+	mux.HandleFunc("POST /api/users/{id}/mute", apiCfg.middlewareRequireAuth(muteUser(apiCfg), auth.ScopeProfileWrite))
+	mux.HandleFunc("DELETE /api/users/{id}/mute", apiCfg.middlewareRequireAuth(unmuteUser(apiCfg), auth.ScopeProfileWrite))
+	mux.HandleFunc("GET /api/users/me/blocks", apiCfg.middlewareRequireAuth(getBlockedUsers(apiCfg)))
+	mux.HandleFunc("GET /api/users/me/mutes", apiCfg.middlewareRequireAuth(getMutedUsers(apiCfg)))
+	mux.HandleFunc("GET /api/timeline", apiCfg.middlewareRequireAuth(getTimeline(apiCfg), auth.ScopeChirpsRead))
+	mux.HandleFunc("GET /api/users/by-handle/{handle}", getUserByHandle(apiCfg))
+	mux.HandleFunc("GET /api/users/me/export", exportUserData(apiCfg))
+	mux.HandleFunc("DELETE /api/users/me", deleteAccount(apiCfg))
+	mux.HandleFunc("DELETE /api/users/me/deletion", cancelAccountDeletion(apiCfg))
+	mux.HandleFunc("PUT /api/users", apiCfg.middlewareRequireSession(changeEmailPassword(apiCfg)))
+	mux.HandleFunc("POST /api/password-reset", requestPasswordReset(apiCfg))
+	mux.HandleFunc("POST /api/password-reset/confirm", confirmPasswordReset(apiCfg))
+	mux.HandleFunc("GET /api/verify-email", verifyEmail(apiCfg))
+	mux.HandleFunc("POST /api/verify-email/resend", resendEmailVerification(apiCfg))
 	mux.HandleFunc("POST /api/polka/webhooks", eventUserUpgraded(apiCfg))
 
 	defer httpServer.Close()
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hrncacz/go-chirpy/internal/auth"
+)
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	UserID uuid.UUID
+	Role   string
+	Scopes []string
+	// Session is set for access tokens from a first-party login. Personal
+	// access tokens and tokens issued to OAuth clients are not sessions.
+	Session bool
+}
+
+func (p principal) hasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+func (p principal) hasRole(roles ...string) bool {
+	return slices.Contains(roles, p.Role)
+}
+
+type principalContextKey struct{}
+
+// principalFromContext returns the principal stored by
+// middlewareRequireAuth.
+func principalFromContext(ctx context.Context) principal {
+	p, _ := ctx.Value(principalContextKey{}).(principal)
+	return p
+}
+
+// middlewareRequireAuth accepts either a JWT or a personal access token that
+// grants all of the scopes and stores the caller in the request context.
+func (cfg *apiConfig) middlewareRequireAuth(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.GetBearerToken(r.Header); err != nil {
+			errorMessage := "Unauthorized"
+			responseError(w, errorMessage, 401)
+			return
+		}
+		p, ok := cfg.authenticate(w, r)
+		if !ok {
+			return
+		}
+		for _, scope := range scopes {
+			if !p.hasScope(scope) {
+				errorMessage := fmt.Sprintf("Token is missing the %s scope", scope)
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+				responseError(w, errorMessage, 403)
+				return
+			}
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, p)))
+	}
+}
+
+// middlewareRequireSession is middlewareRequireAuth for credential changes
+// and managing OAuth clients. A leaked personal access token or a token
+// granted to another app must not be enough to take over the account, so
+// only first-party sessions are accepted.
+func (cfg *apiConfig) middlewareRequireSession(next http.HandlerFunc) http.HandlerFunc {
+	return cfg.middlewareRequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !principalFromContext(r.Context()).Session {
+			errorMessage := "This requires a signed in session"
+			responseError(w, errorMessage, 403)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// middlewareOptionalAuth is for public endpoints that show more to signed in
+// callers. Requests without a token pass with no principal, but a token that
+// is sent must be valid.
+func (cfg *apiConfig) middlewareOptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.GetBearerToken(r.Header); err != nil {
+			next(w, r)
+			return
+		}
+		p, ok := cfg.authenticate(w, r)
+		if !ok {
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, p)))
+	}
+}
+
+// authenticate resolves the bearer token to a principal. It answers the
+// request itself and returns false when the token is not valid.
+func (cfg *apiConfig) authenticate(w http.ResponseWriter, r *http.Request) (principal, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		errorMessage := "Unauthorized"
+		responseError(w, errorMessage, 401)
+		return principal{}, false
+	}
+	if auth.BearerTokenKind(token) != auth.TokenKindJWT {
+		p, err := personalAccessTokenPrincipal(r, cfg, token)
+		if errors.Is(err, sql.ErrNoRows) {
+			errorMessage := "Invalid token"
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description="%s"`, errorMessage))
+			responseError(w, errorMessage, 401)
+			return principal{}, false
+		}
+		if err != nil {
+			errorMessage := "Cannot check token"
+			responseError(w, errorMessage, 500)
+			return principal{}, false
+		}
+		return p, true
+	}
+	claims, err := validateAccessToken(r.Context(), cfg, token)
+	if err != nil {
+		responseTokenError(w, err)
+		return principal{}, false
+	}
+	if len(claims.ClientID) > 0 && claims.Subject == claims.ClientID {
+		errorMessage := "Token is not issued to a user"
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description="%s"`, errorMessage))
+		responseError(w, errorMessage, 401)
+		return principal{}, false
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		responseTokenError(w, auth.ErrTokenMalformed)
+		return principal{}, false
+	}
+	return principal{
+		UserID:  userID,
+		Role:    claims.GetRole(),
+		Scopes:  claims.GetScopes(),
+		Session: len(claims.ClientID) == 0,
+	}, true
+}
+
+// errTokenCheckUnavailable means the revocation list could not be read, so
+// the token is neither accepted nor reported as invalid.
+var errTokenCheckUnavailable = errors.New("cannot check token revocation")
+
+// validateAccessToken checks the JWT and that it has not been revoked.
+// Tokens issued before they carried a jti cannot be revoked and simply
+// expire.
+func validateAccessToken(ctx context.Context, cfg *apiConfig, token string) (*auth.Claims, error) {
+	claims, err := cfg.jwtValidator.ValidateClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(claims.ID) == 0 {
+		return claims, nil
+	}
+	revoked, err := cfg.db.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		log.Printf("Error checking access token revocation: %s\n", err)
+		return nil, errTokenCheckUnavailable
+	}
+	if revoked {
+		return nil, auth.ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// validateUserAccessToken is validateAccessToken for handlers that only need
+// the user ID in the subject. Like auth.Validator.ValidateJWT it rejects
+// tokens issued to OAuth clients with auth.ErrTokenClient.
+func validateUserAccessToken(ctx context.Context, cfg *apiConfig, token string) (uuid.UUID, error) {
+	claims, err := validateAccessToken(ctx, cfg, token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(claims.ClientID) > 0 {
+		return uuid.Nil, auth.ErrTokenClient
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: subject is not a user ID", auth.ErrTokenMalformed)
+	}
+	return userID, nil
+}
+
+func personalAccessTokenPrincipal(r *http.Request, cfg *apiConfig, token string) (principal, error) {
+	personalAccessToken, err := cfg.db.UsePersonalAccessToken(r.Context(), auth.HashToken(token, cfg.tokenHashSecret))
+	if err != nil {
+		return principal{}, err
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), personalAccessToken.UserID)
+	if err != nil {
+		return principal{}, err
+	}
+	return principal{
+		UserID: user.ID,
+		Role:   user.Role,
+		Scopes: personalAccessToken.Scopes,
+	}, nil
+}
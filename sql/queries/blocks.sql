@@ -0,0 +1,60 @@
+-- name: BlockUser :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :execrows
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocker_id = sqlc.arg(user_a) AND blocked_id = sqlc.arg(user_b))
+	OR (blocker_id = sqlc.arg(user_b) AND blocked_id = sqlc.arg(user_a))
+);
+
+-- name: GetBlockedUsers :many
+SELECT users.* FROM users
+JOIN blocks ON blocks.blocked_id = users.id
+WHERE blocks.blocker_id = $1
+ORDER BY blocks.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetUserBlocks :many
+SELECT * FROM blocks WHERE blocker_id = $1 ORDER BY created_at ASC;
+
+-- name: GetBlockRelatedUserIDs :many
+SELECT blocked_id AS user_id FROM blocks WHERE blocks.blocker_id = sqlc.arg(user_id)
+UNION
+SELECT blocker_id AS user_id FROM blocks WHERE blocks.blocked_id = sqlc.arg(user_id);
+
+-- name: MuteUser :execrows
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :execrows
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT users.* FROM users
+JOIN mutes ON mutes.muted_id = users.id
+WHERE mutes.muter_id = $1
+ORDER BY mutes.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetUserMutes :many
+SELECT * FROM mutes WHERE muter_id = $1 ORDER BY created_at ASC;
+
+-- name: GetMutedUserIDs :many
+SELECT muted_id FROM mutes WHERE muter_id = $1;
@@ -0,0 +1,33 @@
+-- name: LikeChirp :execrows
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM chirp_likes
+WHERE chirp_id = $1 AND user_id = $2;
+
+-- name: GetChirpLikers :many
+SELECT users.* FROM users
+JOIN chirp_likes ON chirp_likes.user_id = users.id
+WHERE chirp_likes.chirp_id = $1 AND users.deletion_scheduled_at IS NULL
+ORDER BY chirp_likes.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetUserLikedChirps :many
+SELECT chirps.* FROM chirps
+JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirp_likes.user_id = $1
+ORDER BY chirp_likes.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetLikedChirpIDs :many
+SELECT chirp_id FROM chirp_likes
+WHERE user_id = sqlc.arg(user_id) AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetUserLikes :many
+SELECT * FROM chirp_likes WHERE user_id = $1 ORDER BY created_at ASC;
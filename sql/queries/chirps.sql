@@ -1,11 +1,14 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, root_id, quote_of_id)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3,
+	$4,
+	$5
 )
 RETURNING *;
 
@@ -25,3 +28,56 @@ SELECT * FROM chirps WHERE id = $1;
 DELETE FROM chirps
 WHERE id = $1 AND user_id = $2;
 
+
+-- name: EditChirp :one
+UPDATE chirps
+SET body = $2,
+edit_count = edit_count + 1,
+edited_at = NOW(),
+updated_at = NOW()
+WHERE id = $1 AND edit_count = $3
+RETURNING *;
+
+-- name: CreateChirpRevision :exec
+INSERT INTO chirp_revisions (chirp_id, version, body, created_at, replaced_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	NOW()
+);
+
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY version ASC;
+
+-- name: GetChirpReplies :many
+SELECT * FROM chirps
+WHERE in_reply_to_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT $2 OFFSET $3;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+	SELECT parent.* FROM chirps parent
+	JOIN chirps child ON child.in_reply_to_id = parent.id
+	WHERE child.id = $1
+	UNION ALL
+	SELECT chirps.* FROM chirps
+	JOIN ancestors ON ancestors.in_reply_to_id = chirps.id
+)
+SELECT * FROM ancestors
+ORDER BY created_at ASC;
+
+-- name: GetChirpReplyTree :many
+WITH RECURSIVE tree AS (
+	SELECT * FROM chirps WHERE chirps.in_reply_to_id = $1
+	UNION ALL
+	SELECT chirps.* FROM chirps
+	JOIN tree ON chirps.in_reply_to_id = tree.id
+)
+SELECT * FROM tree
+ORDER BY created_at ASC
+LIMIT $2;
@@ -0,0 +1,20 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, created_at, expires_at, email, user_id)
+VALUES (
+	$1,
+	NOW(),
+	$2,
+	$3,
+	$4
+);
+
+-- name: ConsumeEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND expires_at > NOW() AND used_at IS NULL
+RETURNING user_id, email;
+
+-- name: InvalidateUserEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
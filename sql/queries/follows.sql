@@ -0,0 +1,31 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :execrows
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowers :many
+SELECT users.* FROM users
+JOIN follows ON follows.follower_id = users.id
+WHERE follows.followee_id = $1 AND users.deletion_scheduled_at IS NULL
+ORDER BY follows.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetFollowing :many
+SELECT users.* FROM users
+JOIN follows ON follows.followee_id = users.id
+WHERE follows.follower_id = $1 AND users.deletion_scheduled_at IS NULL
+ORDER BY follows.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetUserFollows :many
+SELECT * FROM follows
+WHERE follower_id = $1 OR followee_id = $1
+ORDER BY created_at ASC;
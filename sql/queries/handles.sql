@@ -0,0 +1,47 @@
+-- name: GetUserByHandle :one
+SELECT * FROM users WHERE LOWER(handle) = LOWER(sqlc.arg(handle));
+
+-- name: SetUserHandle :one
+UPDATE users
+SET handle = $2,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateHandleRedirect :exec
+INSERT INTO handle_redirects (old_handle, user_id, created_at, expires_at)
+VALUES (
+	LOWER(sqlc.arg(old_handle)),
+	sqlc.arg(user_id),
+	NOW(),
+	sqlc.arg(expires_at)
+)
+ON CONFLICT (old_handle) DO UPDATE
+SET user_id = EXCLUDED.user_id,
+created_at = EXCLUDED.created_at,
+expires_at = EXCLUDED.expires_at;
+
+-- name: GetHandleRedirect :one
+SELECT * FROM handle_redirects
+WHERE old_handle = LOWER(sqlc.arg(old_handle)) AND expires_at > NOW();
+
+-- name: DeleteHandleRedirect :exec
+DELETE FROM handle_redirects
+WHERE old_handle = LOWER(sqlc.arg(old_handle));
+
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES (
+	$1,
+	$2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetChirpsMentioningUser :many
+SELECT chirps.* FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg(user_id)
+ORDER BY chirps.created_at ASC;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_id = $1;
@@ -0,0 +1,17 @@
+-- name: GetLoginAttempts :one
+SELECT * FROM login_attempts WHERE key = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_attempts (key, failures, last_failure_at)
+VALUES (
+	@key,
+	1,
+	@now
+)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE WHEN login_attempts.last_failure_at < @reset_before THEN 1 ELSE login_attempts.failures + 1 END,
+last_failure_at = @now
+RETURNING *;
+
+-- name: ResetLoginAttempts :exec
+DELETE FROM login_attempts WHERE key = $1;
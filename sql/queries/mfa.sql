@@ -0,0 +1,66 @@
+-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2,
+totp_enabled_at = NULL,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(),
+totp_last_step = $2,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+totp_enabled_at = NULL,
+totp_last_step = 0,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserTOTPLastStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND totp_last_step < $2;
+
+-- name: CreateMFATicket :exec
+INSERT INTO mfa_tickets (token_hash, created_at, expires_at, user_id)
+VALUES (
+	$1,
+	NOW(),
+	$2,
+	$3
+);
+
+-- name: GetMFATicket :one
+SELECT * FROM mfa_tickets
+WHERE token_hash = $1 AND expires_at > NOW() AND used_at IS NULL AND failed_attempts < 5;
+
+-- name: RecordMFATicketFailure :exec
+UPDATE mfa_tickets
+SET failed_attempts = failed_attempts + 1
+WHERE token_hash = $1;
+
+-- name: ConsumeMFATicket :execrows
+UPDATE mfa_tickets
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (code_hash, created_at, user_id)
+VALUES (
+	$1,
+	NOW(),
+	$2
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = NOW()
+WHERE code_hash = $1 AND user_id = $2 AND used_at IS NULL;
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
@@ -0,0 +1,24 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, created_at, expires_at, user_id)
+VALUES (
+	$1,
+	NOW(),
+	$2,
+	$3
+);
+
+-- name: GetPasswordResetTokenEmail :one
+SELECT users.email FROM password_reset_tokens
+INNER JOIN users ON users.id = password_reset_tokens.user_id
+WHERE token_hash = $1 AND expires_at > NOW() AND used_at IS NULL;
+
+-- name: ConsumePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND expires_at > NOW() AND used_at IS NULL
+RETURNING user_id;
+
+-- name: InvalidateUserPasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
@@ -0,0 +1,37 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (id, token_hash, name, scopes, created_at, expires_at, user_id)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	$2,
+	$3,
+	NOW(),
+	$4,
+	$5
+)
+RETURNING *;
+
+-- name: GetUserPersonalAccessTokens :many
+SELECT * FROM personal_access_tokens
+WHERE user_id = $1 AND expires_at > NOW() AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetPersonalAccessToken :one
+SELECT * FROM personal_access_tokens
+WHERE token_hash = $1 AND expires_at > NOW() AND revoked_at IS NULL;
+
+-- name: UsePersonalAccessToken :one
+UPDATE personal_access_tokens
+SET last_used_at = NOW()
+WHERE token_hash = $1 AND expires_at > NOW() AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeAllUserPersonalAccessTokens :exec
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
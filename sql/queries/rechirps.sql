@@ -0,0 +1,31 @@
+-- name: Rechirp :execrows
+INSERT INTO rechirps (chirp_id, user_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UndoRechirp :execrows
+DELETE FROM rechirps
+WHERE chirp_id = $1 AND user_id = $2;
+
+-- name: GetRechirpsByUserID :many
+SELECT chirps.*, rechirps.created_at AS rechirped_at FROM chirps
+JOIN rechirps ON rechirps.chirp_id = chirps.id
+WHERE rechirps.user_id = $1
+ORDER BY rechirps.created_at ASC;
+
+-- name: GetUserRechirps :many
+SELECT * FROM rechirps WHERE user_id = $1 ORDER BY created_at ASC;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetChirpQuotes :many
+SELECT * FROM chirps
+WHERE quote_of_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
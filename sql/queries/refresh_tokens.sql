@@ -1,20 +1,68 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id,	expires_at)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id,	expires_at, family_id, parent_token_hash, session_started_at, last_used_at, user_agent, ip_address, client_id, scopes)
 VALUES (
 	$1,
 	NOW(),
 	NOW(),
 	$2,
-	$3
+	$3,
+	$4,
+	$5,
+	$6,
+	NOW(),
+	$7,
+	$8,
+	$9,
+	$10
 )
 RETURNING *;
 
 -- name: GetUserFromRefreshToken :one
-SELECT user_id FROM refresh_tokens WHERE token = $1 AND expires_at > NOW() AND revoked_at IS NULL;
+SELECT user_id FROM refresh_tokens WHERE token_hash = $1 AND expires_at > NOW() AND revoked_at IS NULL;
+
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens WHERE token_hash = $1;
 
 -- name: RevokeTokenByToken :one
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
 updated_at = NOW()
-WHERE token = $1
+WHERE token_hash = $1
+RETURNING *;
+
+-- name: ConsumeRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE token_hash = $1 AND expires_at > NOW() AND revoked_at IS NULL AND client_id IS NULL
 RETURNING *;
+
+-- name: ConsumeClientRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE token_hash = $1 AND expires_at > NOW() AND revoked_at IS NULL AND client_id = $2
+RETURNING *;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: GetUserSessions :many
+SELECT family_id, session_started_at, last_used_at, expires_at, user_agent, ip_address FROM refresh_tokens
+WHERE user_id = $1 AND expires_at > NOW() AND revoked_at IS NULL
+ORDER BY last_used_at DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,37 @@
+-- name: FanOutChirp :exec
+INSERT INTO timeline_entries (user_id, chirp_id, created_at)
+SELECT chirps.user_id, chirps.id, chirps.created_at FROM chirps
+WHERE chirps.id = sqlc.arg(chirp_id)
+UNION ALL
+SELECT follows.follower_id, chirps.id, chirps.created_at FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE chirps.id = sqlc.arg(chirp_id)
+ON CONFLICT DO NOTHING;
+
+-- name: BackfillTimeline :exec
+INSERT INTO timeline_entries (user_id, chirp_id, created_at)
+SELECT sqlc.arg(follower_id)::uuid, chirps.id, chirps.created_at FROM chirps
+WHERE chirps.user_id = sqlc.arg(followee_id)
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(max_entries)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveFromTimeline :exec
+DELETE FROM timeline_entries
+WHERE timeline_entries.user_id = sqlc.arg(follower_id)
+AND chirp_id IN (SELECT chirps.id FROM chirps WHERE chirps.user_id = sqlc.arg(followee_id));
+
+-- name: GetTimeline :many
+SELECT chirps.* FROM timeline_entries
+JOIN chirps ON chirps.id = timeline_entries.chirp_id
+WHERE timeline_entries.user_id = sqlc.arg(user_id)
+AND (
+	sqlc.narg(before_created_at)::timestamp IS NULL
+	OR (timeline_entries.created_at, timeline_entries.chirp_id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE mutes.muter_id = timeline_entries.user_id AND mutes.muted_id = chirps.user_id
+)
+ORDER BY timeline_entries.created_at DESC, timeline_entries.chirp_id DESC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,41 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (id, provider, subject, email, created_at, last_login_at, user_id)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	$2,
+	$3,
+	NOW(),
+	NOW(),
+	$4
+)
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities WHERE provider = $1 AND subject = $2;
+
+-- name: UpdateUserIdentityLogin :exec
+UPDATE user_identities
+SET email = $2,
+last_login_at = NOW()
+WHERE id = $1;
+
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, created_at, expires_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	NOW(),
+	$5
+);
+
+-- name: ConsumeOIDCLoginState :one
+UPDATE oidc_login_states
+SET used_at = NOW()
+WHERE state_hash = $1 AND provider = $2 AND expires_at > NOW() AND used_at IS NULL
+RETURNING *;
+
+-- name: GetUserIdentities :many
+SELECT * FROM user_identities WHERE user_id = $1 ORDER BY created_at ASC;
@@ -9,22 +9,95 @@ VALUES (
 )
 RETURNING *;
 
+-- name: CreateExternalUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, email_verified_at)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	NOW(),
+	$1,
+	'unset',
+	$2
+)
+RETURNING *;
+
 -- name: Reset :exec
 DELETE FROM users;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
 -- name: UpdateUsersEmailPassword :one
 UPDATE users
-SET email = $2,
+SET pending_email = CASE WHEN email = $2 THEN NULL ELSE $2 END,
 hashed_password = $3,
 updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, is_chirpy_red;
+RETURNING id, created_at, updated_at, email, is_chirpy_red, email_verified_at, pending_email;
 
 -- name: SetIsChirpyRed :exec
 UPDATE users
 SET is_chirpy_red = true,
 updated_at = NOW()
 WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = sqlc.arg(new_hashed_password)
+WHERE id = sqlc.arg(id)
+AND hashed_password = sqlc.arg(old_hashed_password);
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email = $2,
+email_verified_at = NOW(),
+pending_email = NULL,
+updated_at = NOW()
+WHERE id = $1 AND (email = $2 OR pending_email = $2)
+RETURNING *;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users
+SET deletion_scheduled_at = $2,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = NULL,
+updated_at = NOW()
+WHERE id = $1 AND deletion_scheduled_at IS NOT NULL;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
+-- name: PurgeDeletedUsers :execrows
+DELETE FROM users WHERE deletion_scheduled_at <= NOW();
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET display_name = COALESCE(sqlc.narg(display_name), display_name),
+bio = COALESCE(sqlc.narg(bio), bio),
+avatar_url = COALESCE(sqlc.narg(avatar_url), avatar_url),
+location = COALESCE(sqlc.narg(location), location),
+website = COALESCE(sqlc.narg(website), website),
+updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateUserEmail :one
+UPDATE users
+SET pending_email = CASE WHEN email = $2 THEN NULL ELSE $2 END,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
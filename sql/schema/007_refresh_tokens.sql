@@ -0,0 +1,19 @@
+-- +goose up
+-- Existing tokens were stored in plaintext and cannot be converted without
+-- the server secret, so they are dropped and users have to log in again.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN parent_token TO parent_token_hash;
+
+-- +goose down
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN parent_token_hash TO parent_token;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
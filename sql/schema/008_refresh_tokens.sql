@@ -0,0 +1,21 @@
+-- +goose up
+ALTER TABLE refresh_tokens
+ADD COLUMN session_started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+ADD COLUMN last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+
+UPDATE refresh_tokens
+SET session_started_at = created_at,
+last_used_at = updated_at;
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens(user_id);
+
+-- +goose down
+DROP INDEX refresh_tokens_user_id_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN ip_address,
+DROP COLUMN user_agent,
+DROP COLUMN last_used_at,
+DROP COLUMN session_started_at;
@@ -0,0 +1,9 @@
+-- +goose up
+CREATE TABLE login_attempts (
+	key TEXT PRIMARY KEY,
+	failures INTEGER NOT NULL,
+	last_failure_at TIMESTAMP NOT NULL
+);
+
+-- +goose down
+DROP TABLE login_attempts;
@@ -0,0 +1,17 @@
+-- +goose up
+CREATE TABLE personal_access_tokens (
+	id UUID PRIMARY KEY,
+	token_hash TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	scopes TEXT[] NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX personal_access_tokens_user_id_idx ON personal_access_tokens (user_id);
+
+-- +goose down
+DROP TABLE personal_access_tokens;
@@ -0,0 +1,41 @@
+-- +goose up
+CREATE TABLE oauth_clients (
+	id TEXT PRIMARY KEY,
+	-- Public clients such as mobile apps have no secret and rely on PKCE.
+	secret_hash TEXT,
+	name TEXT NOT NULL,
+	redirect_uris TEXT[] NOT NULL,
+	scopes TEXT[] NOT NULL,
+	grant_types TEXT[] NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE oauth_authorization_codes (
+	code_hash TEXT PRIMARY KEY,
+	client_id TEXT NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	redirect_uri TEXT NOT NULL,
+	scopes TEXT[] NOT NULL,
+	code_challenge TEXT NOT NULL,
+	nonce TEXT NOT NULL,
+	auth_time TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	-- Set once tokens are issued so a replayed code can revoke them.
+	family_id UUID
+);
+
+ALTER TABLE refresh_tokens
+ADD COLUMN client_id TEXT REFERENCES oauth_clients(id) ON DELETE CASCADE,
+ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose down
+ALTER TABLE refresh_tokens
+DROP COLUMN scopes,
+DROP COLUMN client_id;
+
+DROP TABLE oauth_authorization_codes;
+
+DROP TABLE oauth_clients;
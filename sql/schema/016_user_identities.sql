@@ -0,0 +1,26 @@
+-- +goose up
+CREATE TABLE user_identities (
+	id UUID PRIMARY KEY,
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	last_login_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	UNIQUE (provider, subject)
+);
+
+CREATE TABLE oidc_login_states (
+	state_hash TEXT PRIMARY KEY,
+	provider TEXT NOT NULL,
+	nonce TEXT NOT NULL,
+	code_verifier TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP
+);
+
+-- +goose down
+DROP TABLE oidc_login_states;
+
+DROP TABLE user_identities;
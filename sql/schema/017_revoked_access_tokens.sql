@@ -0,0 +1,11 @@
+-- +goose up
+CREATE TABLE revoked_access_tokens (
+	jti TEXT PRIMARY KEY,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX revoked_access_tokens_expires_at_idx ON revoked_access_tokens (expires_at);
+
+-- +goose down
+DROP TABLE revoked_access_tokens;
@@ -0,0 +1,15 @@
+-- +goose up
+ALTER TABLE users
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '',
+ADD COLUMN location TEXT NOT NULL DEFAULT '',
+ADD COLUMN website TEXT NOT NULL DEFAULT '';
+
+-- +goose down
+ALTER TABLE users
+DROP COLUMN website,
+DROP COLUMN location,
+DROP COLUMN avatar_url,
+DROP COLUMN bio,
+DROP COLUMN display_name;
@@ -0,0 +1,33 @@
+-- +goose up
+ALTER TABLE users
+ADD COLUMN handle TEXT;
+
+-- Handles keep the case their owner chose but are unique regardless of case.
+CREATE UNIQUE INDEX users_handle_idx ON users (LOWER(handle));
+
+-- A changed handle keeps pointing to its old owner for a while so links and
+-- mentions do not break and nobody can take it over right away.
+CREATE TABLE handle_redirects (
+	old_handle TEXT PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE chirp_mentions (
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+-- +goose down
+DROP TABLE chirp_mentions;
+
+DROP TABLE handle_redirects;
+
+DROP INDEX users_handle_idx;
+
+ALTER TABLE users
+DROP COLUMN handle;
@@ -0,0 +1,21 @@
+-- +goose up
+ALTER TABLE chirps
+ADD COLUMN edited_at TIMESTAMP,
+ADD COLUMN edit_count INTEGER NOT NULL DEFAULT 0;
+
+-- Earlier versions of edited chirps. The current version stays in chirps.
+CREATE TABLE chirp_revisions (
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	version INTEGER NOT NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	replaced_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (chirp_id, version)
+);
+
+-- +goose down
+DROP TABLE chirp_revisions;
+
+ALTER TABLE chirps
+DROP COLUMN edit_count,
+DROP COLUMN edited_at;
@@ -0,0 +1,44 @@
+-- +goose up
+-- root_id names the conversation and deliberately has no foreign key so a
+-- thread stays together after its first chirp is deleted.
+ALTER TABLE chirps
+ADD COLUMN in_reply_to_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN root_id UUID,
+ADD COLUMN reply_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX chirps_in_reply_to_id_idx ON chirps (in_reply_to_id, created_at);
+
+CREATE INDEX chirps_root_id_idx ON chirps (root_id);
+
+-- reply_count is kept by a trigger so replies removed by cascading deletes
+-- are counted too.
+-- +goose StatementBegin
+CREATE FUNCTION chirps_reply_count() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to_id;
+	ELSE
+		UPDATE chirps SET reply_count = reply_count - 1 WHERE id = OLD.in_reply_to_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_reply_count
+AFTER INSERT OR DELETE ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirps_reply_count();
+
+-- +goose down
+DROP TRIGGER chirps_reply_count ON chirps;
+
+DROP FUNCTION chirps_reply_count();
+
+DROP INDEX chirps_root_id_idx;
+
+DROP INDEX chirps_in_reply_to_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN reply_count,
+DROP COLUMN root_id,
+DROP COLUMN in_reply_to_id;
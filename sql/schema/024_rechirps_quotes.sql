@@ -0,0 +1,70 @@
+-- +goose up
+CREATE TABLE rechirps (
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX rechirps_user_id_idx ON rechirps (user_id, created_at);
+
+-- quote_of_id has no foreign key so a quote keeps pointing at its original
+-- after the original is deleted and can be shown with a tombstone.
+ALTER TABLE chirps
+ADD COLUMN quote_of_id UUID,
+ADD COLUMN rechirp_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN quote_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX chirps_quote_of_id_idx ON chirps (quote_of_id, created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION rechirps_count() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		UPDATE chirps SET rechirp_count = rechirp_count + 1 WHERE id = NEW.chirp_id;
+	ELSE
+		UPDATE chirps SET rechirp_count = rechirp_count - 1 WHERE id = OLD.chirp_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER rechirps_count
+AFTER INSERT OR DELETE ON rechirps
+FOR EACH ROW EXECUTE FUNCTION rechirps_count();
+
+-- +goose StatementBegin
+CREATE FUNCTION chirps_quote_count() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		UPDATE chirps SET quote_count = quote_count + 1 WHERE id = NEW.quote_of_id;
+	ELSE
+		UPDATE chirps SET quote_count = quote_count - 1 WHERE id = OLD.quote_of_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_quote_count
+AFTER INSERT OR DELETE ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirps_quote_count();
+
+-- +goose down
+DROP TRIGGER chirps_quote_count ON chirps;
+
+DROP FUNCTION chirps_quote_count();
+
+DROP TRIGGER rechirps_count ON rechirps;
+
+DROP FUNCTION rechirps_count();
+
+DROP INDEX chirps_quote_of_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN quote_count,
+DROP COLUMN rechirp_count,
+DROP COLUMN quote_of_id;
+
+DROP TABLE rechirps;
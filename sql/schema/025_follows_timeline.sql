@@ -0,0 +1,63 @@
+-- +goose up
+CREATE TABLE follows (
+	follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (follower_id, followee_id),
+	CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX follows_followee_id_idx ON follows (followee_id, created_at);
+
+ALTER TABLE users
+ADD COLUMN follower_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN following_count INTEGER NOT NULL DEFAULT 0;
+
+-- +goose StatementBegin
+CREATE FUNCTION follows_count() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		UPDATE users SET follower_count = follower_count + 1 WHERE id = NEW.followee_id;
+		UPDATE users SET following_count = following_count + 1 WHERE id = NEW.follower_id;
+	ELSE
+		UPDATE users SET follower_count = follower_count - 1 WHERE id = OLD.followee_id;
+		UPDATE users SET following_count = following_count - 1 WHERE id = OLD.follower_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER follows_count
+AFTER INSERT OR DELETE ON follows
+FOR EACH ROW EXECUTE FUNCTION follows_count();
+
+-- timeline_entries is each user's home timeline inbox. A new chirp is
+-- written to the inbox of its author and of every follower, so reading a
+-- timeline is a single index range scan however many accounts are followed.
+CREATE TABLE timeline_entries (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX timeline_entries_user_id_idx ON timeline_entries (user_id, created_at DESC, chirp_id DESC);
+
+CREATE INDEX timeline_entries_chirp_id_idx ON timeline_entries (chirp_id);
+
+INSERT INTO timeline_entries (user_id, chirp_id, created_at)
+SELECT user_id, id, created_at FROM chirps;
+
+-- +goose down
+DROP TABLE timeline_entries;
+
+DROP TRIGGER follows_count ON follows;
+
+DROP FUNCTION follows_count();
+
+ALTER TABLE users
+DROP COLUMN following_count,
+DROP COLUMN follower_count;
+
+DROP TABLE follows;
@@ -2,8 +2,16 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hrncacz/go-chirpy/internal/auth"
 )
 
 func responseError(w http.ResponseWriter, errorMessage string, code int) {
@@ -26,3 +34,123 @@ func responseError(w http.ResponseWriter, errorMessage string, code int) {
 	w.WriteHeader(code)
 	w.Write(dat)
 }
+
+// responseTokenError answers a request whose access token failed validation
+// with a 401 describing why, or with a 503 when the token could not be
+// checked. Tokens of OAuth clients get a 403 from endpoints that need a
+// first-party session.
+func responseTokenError(w http.ResponseWriter, err error) {
+	if errors.Is(err, auth.ErrTokenClient) {
+		errorMessage := "This requires a signed in session"
+		responseError(w, errorMessage, 403)
+		return
+	}
+	if errors.Is(err, errTokenCheckUnavailable) {
+		errorMessage := "Cannot check token"
+		responseError(w, errorMessage, 503)
+		return
+	}
+	errorMessage := "Invalid token"
+	switch {
+	case errors.Is(err, auth.ErrTokenExpired):
+		errorMessage = "Token has expired"
+	case errors.Is(err, auth.ErrTokenNotValidYet):
+		errorMessage = "Token is not valid yet"
+	case errors.Is(err, auth.ErrTokenSignature):
+		errorMessage = "Invalid token signature"
+	case errors.Is(err, auth.ErrTokenAlgorithm):
+		errorMessage = "Token signing method is not allowed"
+	case errors.Is(err, auth.ErrTokenIssuer):
+		errorMessage = "Token has wrong issuer"
+	case errors.Is(err, auth.ErrTokenAudience):
+		errorMessage = "Token has wrong audience"
+	case errors.Is(err, auth.ErrTokenMissingClaim):
+		errorMessage = "Token is missing a required claim"
+	case errors.Is(err, auth.ErrTokenRevoked):
+		errorMessage = "Token has been revoked"
+	case errors.Is(err, auth.ErrTokenMalformed):
+		errorMessage = "Malformed token"
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description="%s"`, errorMessage))
+	responseError(w, errorMessage, 401)
+}
+
+// clientIP returns the address of the client that sent the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// responseTooManyRequests answers with 429 and tells the client when it may
+// try again.
+func responseTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	errorMessage := fmt.Sprintf("Too many failed attempts, try again in %d seconds", seconds)
+	responseError(w, errorMessage, 429)
+}
+
+// responsePasswordViolations answers with 400 and lists every password policy
+// rule the password failed.
+func responsePasswordViolations(w http.ResponseWriter, violations []auth.PasswordViolation) {
+	type resError struct {
+		Error      string                   `json:"error"`
+		Violations []auth.PasswordViolation `json:"violations"`
+	}
+
+	res := resError{
+		Error:      "Password does not meet the requirements",
+		Violations: violations,
+	}
+
+	dat, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("Error marshalling error: %s\n", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+	w.Write(dat)
+}
+
+// checkPasswordPolicy answers with the policy violations and returns false
+// when the password is not acceptable for the given email address.
+func checkPasswordPolicy(w http.ResponseWriter, cfg *apiConfig, password, email string) bool {
+	violations := cfg.passwordPolicy.Check(password, email)
+	if len(violations) > 0 {
+		responsePasswordViolations(w, violations)
+		return false
+	}
+	return true
+}
+
+// Page sizes for list endpoints that take limit and offset.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parsePagination reads the limit and offset query parameters.
+func parsePagination(r *http.Request) (limit, offset int32, err error) {
+	limit = defaultPageSize
+	if value := r.URL.Query().Get("limit"); len(value) > 0 {
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = int32(parsed)
+	}
+	if value := r.URL.Query().Get("offset"); len(value) > 0 {
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || parsed < 0 {
+			return 0, 0, errors.New("offset must not be negative")
+		}
+		offset = int32(parsed)
+	}
+	return limit, offset, nil
+}